@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Version of the archive layout; restore refuses archives newer than it knows.
+// An archive is a gzipped tar holding manifest.json followed by one
+// <collection>.jsonl file per collection, with a document per line in
+// canonical extended JSON so that ObjectIds, dates and number types survive.
+const Version = 1
+
+const manifestName = "manifest.json"
+
+// Collections are dumped and restored in this order, so a document is always
+// written after the documents it refers to. Every collection the application
+// writes has to be listed: Dump refuses a database holding one that is not.
+var Collections = []string{
+	"user", "menu", "food", "combo", "table",
+	"supplier", "ingredient", "recipe", "purchaseOrder",
+	"taxRate", "serviceChargeRule", "promotion", "printer", "tillSession",
+	"order", "orderItem", "invoice", "payment", "refund", "waste", "printJob",
+	"dayReport",
+	// the invoice number sequences, without them numbering starts over
+	"counter",
+}
+
+// the migration records are left out: the migrations build the indexes of the
+// restored database again when it is first started
+var skipped = map[string]bool{"migration": true}
+
+// fails on a collection of the database that is neither in Collections nor
+// skipped, so that a dump never silently leaves data behind
+func checkCollections(ctx context.Context, database *mongo.Database) error {
+	names, err := database.ListCollectionNames(ctx, bson.M{})
+
+	if err != nil {
+		return err
+	}
+
+	known := map[string]bool{}
+
+	for _, collection := range Collections {
+		known[collection] = true
+	}
+
+	for _, name := range names {
+		if !known[name] && !skipped[name] {
+			return fmt.Errorf("collection %s is not in backup.Collections, add it before dumping", name)
+		}
+	}
+
+	return nil
+}
+
+type Manifest struct {
+	Version     int            `json:"version"`
+	Created_at  time.Time      `json:"created_at"`
+	Anonymized  bool           `json:"anonymized"`
+	Collections map[string]int `json:"collections"` // documents per collection
+}
+
+// a field holding the id of a document of another collection
+type reference struct {
+	collection string
+	field      string
+	target     string
+	targetId   string
+}
+
+var references = []reference{
+	{collection: "food", field: "menu_id", target: "menu", targetId: "menu_id"},
+	{collection: "ingredient", field: "supplier_id", target: "supplier", targetId: "supplier_id"},
+	{collection: "recipe", field: "food_id", target: "food", targetId: "food_id"},
+	{collection: "purchaseOrder", field: "supplier_id", target: "supplier", targetId: "supplier_id"},
+	{collection: "tillSession", field: "user_id", target: "user", targetId: "user_id"},
+	{collection: "order", field: "table_id", target: "table", targetId: "table_id"},
+	{collection: "orderItem", field: "order_id", target: "order", targetId: "order_id"},
+	{collection: "orderItem", field: "food_id", target: "food", targetId: "food_id"},
+	{collection: "orderItem", field: "combo_id", target: "combo", targetId: "combo_id"},
+	{collection: "invoice", field: "order_id", target: "order", targetId: "order_id"},
+	{collection: "payment", field: "invoice_id", target: "invoice", targetId: "invoice_id"},
+	{collection: "payment", field: "till_session_id", target: "tillSession", targetId: "till_session_id"},
+	{collection: "refund", field: "invoice_id", target: "invoice", targetId: "invoice_id"},
+	{collection: "refund", field: "payment_id", target: "payment", targetId: "payment_id"},
+	{collection: "waste", field: "ingredient_id", target: "ingredient", targetId: "ingredient_id"},
+	{collection: "waste", field: "food_id", target: "food", targetId: "food_id"},
+	{collection: "printJob", field: "printer_id", target: "printer", targetId: "printer_id"},
+}
+
+// integrity collects the ids of the collections that are referred to and
+// checks each reference against them; collections have to be fed in the
+// order of Collections
+type integrity struct {
+	ids      map[string]map[string]bool
+	dangling []string
+	count    int
+}
+
+// at most this many dangling references are listed, the rest are only counted
+const danglingExamples = 20
+
+func newIntegrity() *integrity {
+	return &integrity{ids: map[string]map[string]bool{}}
+}
+
+func (check *integrity) add(collection string, document bson.Raw) {
+	for _, ref := range references {
+		if ref.target == collection {
+			if id, ok := document.Lookup(ref.targetId).StringValueOK(); ok {
+				if check.ids[collection] == nil {
+					check.ids[collection] = map[string]bool{}
+				}
+
+				check.ids[collection][id] = true
+			}
+		}
+
+		if ref.collection == collection {
+			id, ok := document.Lookup(ref.field).StringValueOK()
+
+			if !ok || id == "" || check.ids[ref.target][id] {
+				continue
+			}
+
+			check.count++
+
+			if len(check.dangling) < danglingExamples {
+				check.dangling = append(check.dangling, fmt.Sprintf("%s %s refers to missing %s %s", collection, document.Lookup("_id"), ref.target, id))
+			}
+		}
+	}
+}
+
+func (check *integrity) err() error {
+	if check.count == 0 {
+		return nil
+	}
+
+	return &IntegrityError{Count: check.count, Examples: check.dangling}
+}
+
+type IntegrityError struct {
+	Count    int
+	Examples []string
+}
+
+func (err *IntegrityError) Error() string {
+	return fmt.Sprintf("%d dangling references", err.Count)
+}
@@ -0,0 +1,194 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Dump writes every collection of Collections to w as an archive. Dangling
+// references do not stop the dump, they are returned as an *IntegrityError
+// once the archive is complete.
+func Dump(ctx context.Context, database *mongo.Database, w io.Writer, anonymize bool) error {
+	manifest := Manifest{
+		Version:     Version,
+		Created_at:  time.Now().UTC(),
+		Anonymized:  anonymize,
+		Collections: map[string]int{},
+	}
+
+	if err := checkCollections(ctx, database); err != nil {
+		return err
+	}
+
+	check := newIntegrity()
+	files := map[string]*os.File{}
+
+	defer func() {
+		for _, file := range files {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	// the tar header needs each file's size, so collections are spooled to
+	// temporary files first
+	for _, collection := range Collections {
+		file, err := os.CreateTemp("", "dump-"+collection+"-*.jsonl")
+
+		if err != nil {
+			return err
+		}
+
+		files[collection] = file
+
+		count, err := dumpCollection(ctx, database.Collection(collection), file, check, anonymize)
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", collection, err)
+		}
+
+		manifest.Collections[collection] = count
+	}
+
+	compressed := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressed)
+
+	encodedManifest, err := json.MarshalIndent(manifest, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := writeEntry(archive, manifestName, int64(len(encodedManifest)), bytes.NewReader(encodedManifest)); err != nil {
+		return err
+	}
+
+	for _, collection := range Collections {
+		file := files[collection]
+
+		size, err := file.Seek(0, io.SeekEnd)
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		if err := writeEntry(archive, collection+".jsonl", size, file); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	if err := compressed.Close(); err != nil {
+		return err
+	}
+
+	return check.err()
+}
+
+func dumpCollection(ctx context.Context, collection *mongo.Collection, w io.Writer, check *integrity, anonymize bool) (int, error) {
+	cursor, err := collection.Find(ctx, bson.M{})
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer cursor.Close(ctx)
+
+	writer := bufio.NewWriter(w)
+	count := 0
+
+	for cursor.Next(ctx) {
+		document := cursor.Current
+
+		check.add(collection.Name(), document)
+
+		if anonymize && collection.Name() == "user" {
+			document, err = anonymizeUser(document, count+1)
+
+			if err != nil {
+				return count, err
+			}
+		}
+
+		line, err := bson.MarshalExtJSON(document, true, false)
+
+		if err != nil {
+			return count, err
+		}
+
+		writer.Write(line)
+		writer.WriteByte('\n')
+		count++
+	}
+
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+
+	return count, writer.Flush()
+}
+
+// replaces what identifies a person; ids, roles and dates are kept so that the
+// rest of the data still adds up. Passwords and tokens are dropped, so
+// anonymized users cannot log in.
+func anonymizeUser(document bson.Raw, number int) (bson.Raw, error) {
+	var user bson.D
+
+	if err := bson.Unmarshal(document, &user); err != nil {
+		return nil, err
+	}
+
+	replacements := map[string]interface{}{
+		"first_name":    "User",
+		"last_name":     fmt.Sprint(number),
+		"email":         fmt.Sprintf("user%d@example.invalid", number),
+		"phone":         fmt.Sprintf("+000%07d", number),
+		"avatar":        nil,
+		"password":      nil,
+		"token":         nil,
+		"refresh_token": nil,
+	}
+
+	for i, field := range user {
+		if value, ok := replacements[field.Key]; ok {
+			user[i].Value = value
+		}
+	}
+
+	return bson.Marshal(user)
+}
+
+func writeEntry(archive *tar.Writer, name string, size int64, content io.Reader) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    size,
+		ModTime: time.Now().UTC(),
+	}
+
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := io.Copy(archive, content)
+
+	return err
+}
@@ -0,0 +1,196 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// documents inserted per InsertMany call
+const restoreBatch = 1000
+
+// Restore loads the archive at path into database, whose collections have to
+// be empty. The archive is read twice: first to check it, references
+// included, so that nothing is written from an archive that cannot be fully
+// restored; then to insert the documents.
+func Restore(ctx context.Context, database *mongo.Database, path string, allowDangling bool) (*Manifest, error) {
+	names, err := database.ListCollectionNames(ctx, bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, collection := range names {
+		if skipped[collection] {
+			continue
+		}
+
+		count, err := database.Collection(collection).CountDocuments(ctx, bson.M{})
+
+		if err != nil {
+			return nil, err
+		}
+
+		if count > 0 {
+			return nil, fmt.Errorf("collection %s is not empty, restore only into an empty database", collection)
+		}
+	}
+
+	check := newIntegrity()
+
+	manifest, err := readArchive(path, func(collection string, document bson.Raw) error {
+		check.add(collection, document)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := check.err(); err != nil && !allowDangling {
+		return manifest, err
+	}
+
+	batches := map[string][]interface{}{}
+
+	flush := func(collection string) error {
+		if len(batches[collection]) == 0 {
+			return nil
+		}
+
+		_, err := database.Collection(collection).InsertMany(ctx, batches[collection])
+		batches[collection] = nil
+
+		return err
+	}
+
+	_, err = readArchive(path, func(collection string, document bson.Raw) error {
+		batches[collection] = append(batches[collection], document)
+
+		if len(batches[collection]) < restoreBatch {
+			return nil
+		}
+
+		return flush(collection)
+	})
+
+	if err != nil {
+		return manifest, fmt.Errorf("restore stopped partway, empty the database before retrying: %w", err)
+	}
+
+	for _, collection := range Collections {
+		if err := flush(collection); err != nil {
+			return manifest, fmt.Errorf("restore stopped partway, empty the database before retrying: %s: %w", collection, err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// reads the manifest and hands every document to handle in archive order,
+// checking the layout version and the document count of each collection
+func readArchive(path string, handle func(collection string, document bson.Raw) error) (*Manifest, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	compressed, err := gzip.NewReader(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	archive := tar.NewReader(compressed)
+
+	header, err := archive.Next()
+
+	if err != nil || header.Name != manifestName {
+		return nil, errors.New("not a backup archive: " + manifestName + " must come first")
+	}
+
+	var manifest Manifest
+
+	if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", manifestName, err)
+	}
+
+	if manifest.Version > Version {
+		return nil, fmt.Errorf("archive version %d is newer than the supported version %d", manifest.Version, Version)
+	}
+
+	known := map[string]bool{}
+
+	for _, collection := range Collections {
+		known[collection] = true
+	}
+
+	seen := map[string]int{}
+
+	for {
+		header, err := archive.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		collection := strings.TrimSuffix(header.Name, ".jsonl")
+
+		if !known[collection] {
+			return nil, errors.New("unexpected file in archive: " + header.Name)
+		}
+
+		scanner := bufio.NewScanner(archive)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+
+			if len(line) == 0 {
+				continue
+			}
+
+			var document bson.Raw
+
+			if err := bson.UnmarshalExtJSON(line, true, &document); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", header.Name, seen[collection]+1, err)
+			}
+
+			if err := handle(collection, document); err != nil {
+				return nil, fmt.Errorf("%s: %w", collection, err)
+			}
+
+			seen[collection]++
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("%s: %w", header.Name, err)
+		}
+	}
+
+	for collection, count := range manifest.Collections {
+		if seen[collection] != count {
+			return nil, fmt.Errorf("archive is incomplete: %s has %d documents, the manifest lists %d", collection, seen[collection], count)
+		}
+	}
+
+	return &manifest, nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go-restaurant-management/backup"
+	"go-restaurant-management/database"
+	"go-restaurant-management/migrations"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const usage = `usage:
+  go-restaurant-management                    start the API server
+  go-restaurant-management dump [-out file] [-anonymize]
+  go-restaurant-management restore -in file [-allow-dangling]
+  go-restaurant-management migrate [-status] [-release version]
+  go-restaurant-management promote -email address`
+
+// runs the command named by args[0] instead of the server
+func runCommand(args []string) error {
+	switch args[0] {
+	case "dump":
+		return dumpCommand(args[1:])
+	case "restore":
+		return restoreCommand(args[1:])
+	case "migrate":
+		return migrateCommand(args[1:])
+	case "promote":
+		return promoteCommand(args[1:])
+	}
+
+	return errors.New(usage)
+}
+
+func dumpCommand(args []string) error {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	out := flags.String("out", "backup-"+time.Now().UTC().Format("20060102-150405")+".tar.gz", "archive to write")
+	anonymize := flags.Bool("anonymize", false, "replace the names, emails and phones of users and drop their passwords")
+	flags.Parse(args)
+
+	file, err := os.Create(*out)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	err = backup.Dump(context.Background(), database.Client.Database(database.Name), file, *anonymize)
+
+	var integrityError *backup.IntegrityError
+
+	// the archive is still written, but it will not restore without -allow-dangling
+	if errors.As(err, &integrityError) {
+		for _, example := range integrityError.Examples {
+			log.Println(example)
+		}
+
+		log.Printf("warning: %s in %s", err, *out)
+		return nil
+	}
+
+	if err != nil {
+		os.Remove(*out)
+		return err
+	}
+
+	fmt.Println("dumped to " + *out)
+
+	return nil
+}
+
+func restoreCommand(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := flags.String("in", "", "archive to restore")
+	allowDangling := flags.Bool("allow-dangling", false, "restore even when documents refer to missing ones")
+	flags.Parse(args)
+
+	if *in == "" {
+		return errors.New(usage)
+	}
+
+	manifest, err := backup.Restore(context.Background(), database.Client.Database(database.Name), *in, *allowDangling)
+
+	var integrityError *backup.IntegrityError
+
+	if errors.As(err, &integrityError) {
+		for _, example := range integrityError.Examples {
+			log.Println(example)
+		}
+
+		return fmt.Errorf("nothing was restored: %w", err)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	for _, collection := range backup.Collections {
+		fmt.Printf("%s: %d\n", collection, manifest.Collections[collection])
+	}
+
+	return nil
+}
+
+func migrateCommand(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	status := flags.Bool("status", false, "list applied and pending migrations without applying any")
+	release := flags.Int("release", 0, "clear the RUNNING record a migration left when its process died, so it is applied again")
+	flags.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	db := database.Client.Database(database.Name)
+
+	if *release > 0 {
+		if err := migrations.Release(ctx, db, *release); err != nil {
+			return err
+		}
+
+		fmt.Printf("released migration %d\n", *release)
+
+		return nil
+	}
+
+	if !*status {
+		return migrate()
+	}
+
+	applied, err := migrations.Applied(ctx, db)
+
+	if err != nil {
+		return err
+	}
+
+	pending, err := migrations.Pending(ctx, db)
+
+	if err != nil {
+		return err
+	}
+
+	recorded := map[int]bool{}
+
+	for _, record := range applied {
+		recorded[record.Version] = true
+		fmt.Printf("%4d  %-8s %s\n", record.Version, record.Status, record.Description)
+	}
+
+	// a RUNNING migration is pending too, it is listed once with its record
+	for _, migration := range pending {
+		if recorded[migration.Version] {
+			continue
+		}
+
+		fmt.Printf("%4d  %-8s %s\n", migration.Version, "PENDING", migration.Description)
+	}
+
+	return nil
+}
+
+func checkTransactions() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return database.CheckTransactions(ctx)
+}
+
+// applies the pending migrations; the server runs this on startup
+func migrate() error {
+	// building an index over a large collection takes a while
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	applied, err := migrations.Run(ctx, database.Client.Database(database.Name))
+
+	for _, version := range applied {
+		log.Printf("applied migration %d", version)
+	}
+
+	return err
+}
+
+// makes a signed up user a manager. Everyone signs up as staff and only
+// managers promote users, so the first manager is made from the command line.
+func promoteCommand(args []string) error {
+	flags := flag.NewFlagSet("promote", flag.ExitOnError)
+	email := flags.String("email", "", "email the user signed up with")
+	flags.Parse(args)
+
+	if *email == "" {
+		return errors.New(usage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	result, err := database.Client.Database(database.Name).Collection("user").UpdateOne(
+		ctx,
+		bson.M{"email": *email},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "user_type", Value: "MANAGER"},
+			{Key: "updated_at", Value: time.Now().UTC().Truncate(time.Second)},
+		}}},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.New("no user signed up with " + *email)
+	}
+
+	// the user type is read from the token, so it applies from the next login
+	fmt.Println(*email + " is a manager from their next login")
+
+	return nil
+}
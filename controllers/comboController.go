@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ComboSelection struct {
+	Combo_id *string  `json:"combo_id" validate:"required"`
+	Quantity *string  `json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
+	Choices  []string `json:"choices" validate:"required,min=1"` // one food_id per combo slot, in slot order
+}
+
+var comboCollection *mongo.Collection = database.OpenCollection(database.Client, "combo")
+
+func GetCombos() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := comboCollection.Find(ctx, bson.M{})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving combos from database"})
+			return
+		}
+
+		var allCombos []bson.M
+
+		if err := result.All(ctx, &allCombos); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allCombos)
+	}
+}
+
+func GetCombo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		comboId := c.Param("combo_id")
+		var combo models.Combo
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := comboCollection.FindOne(ctx, bson.M{"combo_id": comboId}).Decode(&combo)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "combo not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, combo)
+	}
+}
+
+func CreateCombo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var combo models.Combo
+
+		if err := c.BindJSON(&combo); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(combo)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if err := checkComboSlotFoods(ctx, combo.Slots); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var err error
+
+		combo.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+			return
+		}
+
+		combo.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		combo.ID = primitive.NewObjectID()
+		combo.Combo_id = combo.ID.Hex()
+		num := toFixed(*combo.Price, 2)
+		combo.Price = &num
+
+		result, insertErr := comboCollection.InsertOne(ctx, combo)
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "combo is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateCombo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var combo models.Combo
+
+		if err := c.BindJSON(&combo); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		comboId := c.Param("combo_id")
+		var updateObj primitive.D
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if combo.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: combo.Name})
+		}
+
+		if combo.Price != nil {
+			num := toFixed(*combo.Price, 2)
+			updateObj = append(updateObj, bson.E{Key: "price", Value: num})
+		}
+
+		if combo.Slots != nil {
+			if err := validate.Var(combo.Slots, "min=1,dive"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := checkComboSlotFoods(ctx, combo.Slots); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "slots", Value: combo.Slots})
+		}
+
+		var err error
+		combo.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: combo.Updated_at})
+
+		filter := bson.M{"combo_id": comboId}
+
+		result, err := comboCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: updateObj},
+			},
+		)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "combo updated failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "combo not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// every food offered in a slot has to exist
+func checkComboSlotFoods(ctx context.Context, slots []models.ComboSlot) error {
+	for _, slot := range slots {
+		count, err := foodCollection.CountDocuments(ctx, bson.M{"food_id": bson.M{"$in": slot.Food_ids}})
+
+		if err != nil {
+			return err
+		}
+
+		if count != int64(len(slot.Food_ids)) {
+			return errors.New("food not found in combo slot " + *slot.Name)
+		}
+	}
+
+	return nil
+}
+
+// expands a combo selection into the bundle line and one child item per slot for the kitchen
+func expandComboSelection(ctx context.Context, selection ComboSelection, orderId string) ([]models.OrderItem, error) {
+	var combo models.Combo
+
+	if err := comboCollection.FindOne(ctx, bson.M{"combo_id": selection.Combo_id}).Decode(&combo); err != nil {
+		return nil, errors.New("combo not found")
+	}
+
+	if len(selection.Choices) != len(combo.Slots) {
+		return nil, errors.New("combo " + *combo.Name + " needs one choice per slot")
+	}
+
+	parentId := primitive.NewObjectID()
+	parentOrderItemId := parentId.Hex()
+	price := toFixed(*combo.Price, 2)
+
+	items := []models.OrderItem{{
+		ID:            parentId,
+		Quantity:      selection.Quantity,
+		Unit_price:    &price,
+		Combo_id:      &combo.Combo_id,
+		Order_item_id: parentOrderItemId,
+		Order_id:      orderId,
+	}}
+
+	for i, slot := range combo.Slots {
+		foodId := selection.Choices[i]
+
+		if !containsString(slot.Food_ids, foodId) {
+			return nil, errors.New("food " + foodId + " is not a choice for combo slot " + *slot.Name)
+		}
+
+		// components are covered by the bundle price
+		zero := 0.0
+		childId := primitive.NewObjectID()
+
+		items = append(items, models.OrderItem{
+			ID:                   childId,
+			Quantity:             selection.Quantity,
+			Unit_price:           &zero,
+			Food_id:              &foodId,
+			Parent_order_item_id: &parentOrderItemId,
+			Order_item_id:        childId.Hex(),
+			Order_id:             orderId,
+		})
+	}
+
+	return items, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
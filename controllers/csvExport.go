@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// a report is exported as CSV with ?format=csv or an Accept: text/csv header
+func wantsCSV(c *gin.Context) bool {
+	return c.Query("format") == "csv" || strings.Contains(c.GetHeader("Accept"), "text/csv")
+}
+
+func writeCSV(c *gin.Context, filename string, header []string, rows [][]string) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+
+	if err := writer.Write(header); err != nil {
+		log.Println(err)
+		return
+	}
+
+	if err := writer.WriteAll(rows); err != nil {
+		log.Println(err)
+	}
+}
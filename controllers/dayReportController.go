@@ -0,0 +1,468 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	helper "go-restaurant-management/helpers"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var dayReportCollection *mongo.Collection = database.OpenCollection(database.Client, "dayReport")
+
+// X-report: the figures of the business day so far, without closing it
+func GetXReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		start, err := businessDayStart(ctx)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving the last Z-report"})
+			return
+		}
+
+		now, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing the report time"})
+			return
+		}
+
+		report, err := buildDayReport(ctx, start, now)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating the report"})
+			return
+		}
+
+		report.Type = "X"
+		report.User_id = c.GetString("uid")
+		report.Created_at = now
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+var errDayClosed = errors.New("business day has already been closed")
+
+// Z-report: closes the business day and stores its figures for good
+func CreateZReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helper.CheckUserType(c, "MANAGER"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		start, err := businessDayStart(ctx)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving the last Z-report"})
+			return
+		}
+
+		now, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing the report time"})
+			return
+		}
+
+		report, err := buildDayReport(ctx, start, now)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating the report"})
+			return
+		}
+
+		report.Type = "Z"
+		report.User_id = c.GetString("uid")
+		report.Created_at = now
+		report.ID = primitive.NewObjectID()
+		report.Day_report_id = report.ID.Hex()
+
+		// the number is only taken when the report is inserted, so a day closed
+		// twice at the same time does not use up a number
+		err = database.Transaction(ctx, func(ctx context.Context) error {
+			var next counter
+
+			err := counterCollection.FindOneAndUpdate(
+				ctx,
+				bson.M{"_id": "dayReport:" + helper.GetEnvVariable("OUTLET_ID")},
+				bson.D{{Key: "$inc", Value: bson.D{{Key: "seq", Value: 1}}}},
+				options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+			).Decode(&next)
+
+			if err != nil {
+				return err
+			}
+
+			report.Number = next.Seq
+
+			// only inserted when no other Z-report closed the same day in the meantime
+			result, err := dayReportCollection.UpdateOne(
+				ctx,
+				bson.M{"type": "Z", "period_start": start},
+				bson.D{{Key: "$setOnInsert", Value: report}},
+				options.Update().SetUpsert(true),
+			)
+
+			if err != nil {
+				return err
+			}
+
+			if result.UpsertedCount == 0 {
+				return errDayClosed
+			}
+
+			return nil
+		})
+
+		if err == errDayClosed || mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": errDayClosed.Error()})
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Z-report is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+func GetZReports() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := dayReportCollection.Find(ctx, bson.M{"type": "Z"}, options.Find().SetSort(bson.D{{Key: "period_end", Value: -1}}))
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving Z-reports from database"})
+			return
+		}
+
+		var allReports []bson.M
+
+		if err := result.All(ctx, &allReports); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allReports)
+	}
+}
+
+func GetZReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dayReportId := c.Param("day_report_id")
+		var report models.DayReport
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := dayReportCollection.FindOne(ctx, bson.M{"day_report_id": dayReportId}).Decode(&report)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Z-report not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// the business day starts where the last Z-report ended
+func businessDayStart(ctx context.Context) (time.Time, error) {
+	var last models.DayReport
+
+	err := dayReportCollection.FindOne(
+		ctx,
+		bson.M{"type": "Z"},
+		options.FindOne().SetSort(bson.D{{Key: "period_end", Value: -1}}),
+	).Decode(&last)
+
+	if err == mongo.ErrNoDocuments {
+		return time.Unix(0, 0).UTC(), nil
+	}
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return last.Period_end, nil
+}
+
+// aggregates the payments and refunds taken between start and end. Sales are
+// counted as they are paid: every payment brings its share of its invoice's
+// sales, discounts, taxes and service charge, so the sales of a report add up
+// to its tenders and an invoice paid days after it was created is still
+// reported, on the days it was paid.
+func buildDayReport(ctx context.Context, start time.Time, end time.Time) (models.DayReport, error) {
+	report := models.DayReport{
+		Period_start: start,
+		Period_end:   end,
+		Taxes:        []models.ReportTax{},
+		Payments:     []models.ReportPayment{},
+		Refunds:      []models.ReportRefund{},
+	}
+
+	period := bson.D{{Key: "$gte", Value: start}, {Key: "$lt", Value: end}}
+
+	// payments that are still waiting for the card provider are left out
+	paidStage := bson.D{{Key: "$match", Value: bson.D{
+		{Key: "created_at", Value: period},
+		{Key: "status", Value: bson.D{{Key: "$in", Value: bson.A{"COMPLETED", nil}}}},
+	}}}
+
+	sum := func(field string) bson.D {
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{field, 0}}}}}
+	}
+
+	// the part of its invoice's total a payment covers
+	shareStage := bson.D{{Key: "$addFields", Value: bson.D{
+		{Key: "share", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$gt", Value: bson.A{"$invoice.total", 0}}},
+			bson.D{{Key: "$divide", Value: bson.A{"$amount", "$invoice.total"}}},
+			0,
+		}}}},
+	}}}
+
+	shareOf := func(field string) bson.D {
+		return bson.D{{Key: "$sum", Value: bson.D{{Key: "$multiply", Value: bson.A{
+			bson.D{{Key: "$ifNull", Value: bson.A{field, 0}}},
+			"$share",
+		}}}}}
+	}
+
+	facetStage := bson.D{{Key: "$facet", Value: bson.D{
+		{Key: "totals", Value: mongo.Pipeline{
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: nil},
+				{Key: "checks", Value: bson.D{{Key: "$addToSet", Value: "$invoice_id"}}},
+				{Key: "subtotal", Value: shareOf("$invoice.subtotal")},
+				{Key: "discounts", Value: shareOf("$invoice.discount_total")},
+				{Key: "tax_total", Value: shareOf("$invoice.tax_total")},
+				{Key: "service_charge", Value: shareOf("$invoice.service_charge")},
+				{Key: "total", Value: sum("$amount")},
+				{Key: "tips", Value: sum("$tip")},
+			}}},
+			bson.D{{Key: "$addFields", Value: bson.D{
+				{Key: "checks", Value: bson.D{{Key: "$size", Value: "$checks"}}},
+			}}},
+		}},
+		{Key: "taxes", Value: mongo.Pipeline{
+			bson.D{{Key: "$unwind", Value: "$invoice.taxes"}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$invoice.taxes.tax_rate_id"},
+				{Key: "name", Value: bson.D{{Key: "$first", Value: "$invoice.taxes.name"}}},
+				{Key: "amount", Value: shareOf("$invoice.taxes.amount")},
+			}}},
+			bson.D{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "tax_rate_id", Value: "$_id"},
+				{Key: "name", Value: 1},
+				{Key: "amount", Value: 1},
+			}}},
+		}},
+		// a split order is one table, so its guests are counted once
+		{Key: "covers", Value: mongo.Pipeline{
+			bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$invoice.order_id"}}}},
+			bson.D{{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: "order"},
+				{Key: "localField", Value: "_id"},
+				{Key: "foreignField", Value: "order_id"},
+				{Key: "as", Value: "order"},
+			}}},
+			bson.D{{Key: "$unwind", Value: "$order"}},
+			bson.D{{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: "table"},
+				{Key: "localField", Value: "order.table_id"},
+				{Key: "foreignField", Value: "table_id"},
+				{Key: "as", Value: "table"},
+			}}},
+			bson.D{{Key: "$unwind", Value: "$table"}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: nil},
+				{Key: "covers", Value: sum("$table.number_of_guest")},
+			}}},
+		}},
+	}}}
+
+	result, err := paymentCollection.Aggregate(ctx, mongo.Pipeline{
+		paidStage,
+		bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "invoice"},
+			{Key: "localField", Value: "invoice_id"},
+			{Key: "foreignField", Value: "invoice_id"},
+			{Key: "as", Value: "invoice"},
+		}}},
+		bson.D{{Key: "$unwind", Value: "$invoice"}},
+		shareStage,
+		facetStage,
+	})
+
+	if err != nil {
+		return report, err
+	}
+
+	var facets []struct {
+		Totals []struct {
+			Checks         int
+			Subtotal       float64
+			Discounts      float64
+			Tax_total      float64
+			Service_charge float64
+			Total          float64
+			Tips           float64
+		}
+		Taxes  []models.ReportTax
+		Covers []struct {
+			Covers int
+		}
+	}
+
+	if err := result.All(ctx, &facets); err != nil {
+		return report, err
+	}
+
+	if len(facets[0].Totals) > 0 {
+		totals := facets[0].Totals[0]
+
+		report.Checks = totals.Checks
+		report.Discounts = toFixed(totals.Discounts, 2)
+		report.Net_sales = toFixed(totals.Subtotal, 2)
+		report.Gross_sales = toFixed(totals.Subtotal+totals.Discounts, 2)
+		report.Tax_total = toFixed(totals.Tax_total, 2)
+		report.Service_charge = toFixed(totals.Service_charge, 2)
+		report.Total_sales = toFixed(totals.Total, 2)
+		report.Tips = toFixed(totals.Tips, 2)
+	}
+
+	// checks opened before the end of the period and not settled yet, whichever day they were opened
+	openChecks, err := invoiceCollection.CountDocuments(ctx, bson.M{
+		"created_at":     bson.M{"$lt": end},
+		"payment_status": bson.M{"$in": bson.A{"PENDING", "PARTIALLY_PAID"}},
+	})
+
+	if err != nil {
+		return report, err
+	}
+
+	report.Open_checks = int(openChecks)
+
+	for _, tax := range facets[0].Taxes {
+		tax.Amount = toFixed(tax.Amount, 2)
+		report.Taxes = append(report.Taxes, tax)
+	}
+
+	if len(facets[0].Covers) > 0 {
+		report.Covers = facets[0].Covers[0].Covers
+	}
+
+	if report.Checks > 0 {
+		report.Average_check = toFixed(report.Total_sales/float64(report.Checks), 2)
+	}
+
+	paymentResult, err := paymentCollection.Aggregate(ctx, mongo.Pipeline{
+		paidStage,
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$method"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+			{Key: "tips", Value: sum("$tip")},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "method", Value: "$_id"},
+			{Key: "count", Value: 1},
+			{Key: "amount", Value: 1},
+			{Key: "tips", Value: 1},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "method", Value: 1}}}},
+	})
+
+	if err != nil {
+		return report, err
+	}
+
+	var paymentTotals []models.ReportPayment
+
+	if err := paymentResult.All(ctx, &paymentTotals); err != nil {
+		return report, err
+	}
+
+	for _, payment := range paymentTotals {
+		payment.Amount = toFixed(payment.Amount, 2)
+		payment.Tips = toFixed(payment.Tips, 2)
+		report.Payments = append(report.Payments, payment)
+	}
+
+	// card refunds still waiting for, or turned down by, the provider gave nothing back
+	refundResult, err := refundCollection.Aggregate(ctx, mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.D{
+			{Key: "created_at", Value: period},
+			{Key: "status", Value: bson.D{{Key: "$nin", Value: bson.A{"PENDING", "FAILED"}}}},
+		}}},
+		bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$method"},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+		}}},
+		bson.D{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "method", Value: "$_id"},
+			{Key: "count", Value: 1},
+			{Key: "amount", Value: 1},
+		}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "method", Value: 1}}}},
+	})
+
+	if err != nil {
+		return report, err
+	}
+
+	var refundTotals []models.ReportRefund
+
+	if err := refundResult.All(ctx, &refundTotals); err != nil {
+		return report, err
+	}
+
+	refunded := int64(0)
+
+	for _, refund := range refundTotals {
+		refund.Amount = toFixed(refund.Amount, 2)
+		refunded += toCents(refund.Amount)
+		report.Refunds = append(report.Refunds, refund)
+	}
+
+	report.Refund_total = fromCents(refunded)
+
+	return report, nil
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"go-restaurant-management/database"
 	"go-restaurant-management/models"
 	"log"
@@ -37,10 +38,51 @@ func GetFoods() gin.HandlerFunc {
 
 		startIndex := (page - 1) * recordPerPage
 
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		unavailableFoods, err := unavailableFoodIds(ctx)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking food availability"})
+			defer cancel()
+			return
+		}
+
+		if format := exportFormat(c); format != "" {
+			defer cancel()
+
+			filter := bson.M{}
+
+			if c.Query("include_unavailable") != "true" {
+				filter["food_id"] = bson.M{"$nin": unavailableFoods}
+			}
+
+			exportList(c, format, foodCollection, filter, "foods", foodColumns)
+			return
+		}
+
 		matchStage := bson.D{
 			{Key: "$match", Value: bson.D{{}}},
 		}
 
+		// sold-out foods are hidden unless asked for
+		if c.Query("include_unavailable") != "true" {
+			matchStage = bson.D{
+				{Key: "$match", Value: bson.D{
+					{Key: "food_id", Value: bson.D{{Key: "$nin", Value: unavailableFoods}}},
+				}},
+			}
+		}
+
+		addFieldsStage := bson.D{
+			{Key: "$addFields", Value: bson.D{
+				{Key: "available", Value: bson.D{
+					{Key: "$not", Value: bson.A{bson.D{{Key: "$in", Value: bson.A{"$food_id", unavailableFoods}}}}},
+				}},
+			}},
+		}
+
 		groupStage := bson.D{
 			{Key: "$group", Value: bson.D{
 				{Key: "_id", Value: bson.D{
@@ -65,9 +107,7 @@ func GetFoods() gin.HandlerFunc {
 			}},
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-
-		result, err2 := foodCollection.Aggregate(ctx, mongo.Pipeline{matchStage, groupStage, projectStage})
+		result, err2 := foodCollection.Aggregate(ctx, mongo.Pipeline{matchStage, addFieldsStage, groupStage, projectStage})
 		defer cancel()
 
 		if err2 != nil {
@@ -83,6 +123,12 @@ func GetFoods() gin.HandlerFunc {
 			return
 		}
 
+		// every food on the page may be sold out and hidden
+		if len(allFoods) == 0 {
+			c.JSON(http.StatusOK, gin.H{"total_count": 0, "food_items": []bson.M{}})
+			return
+		}
+
 		c.JSON(http.StatusOK, allFoods[0])
 	}
 }
@@ -192,6 +238,19 @@ func UpdateFood() gin.HandlerFunc {
 			updateObj = append(updateObj, bson.E{Key: "food_image", Value: food.Food_image})
 		}
 
+		if food.Availability != nil {
+			if err := validate.Var(food.Availability, "eq=AUTO|eq=AVAILABLE|eq=UNAVAILABLE"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "availability", Value: food.Availability})
+		}
+
+		if food.Station != nil {
+			updateObj = append(updateObj, bson.E{Key: "station", Value: food.Station})
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
 		if food.Menu_id != nil {
@@ -243,6 +302,194 @@ func UpdateFood() gin.HandlerFunc {
 	}
 }
 
+// sizes a food is ordered in
+var foodSizes = []string{"S", "M", "L"}
+
+// the sizes each food is sold out in. A size is sold out when an ingredient of
+// its recipe, the one for that size or else the one for every size, has run
+// out; a manual availability flag overrides the stock for every size.
+func soldOutSizes(ctx context.Context) (map[string]map[string]bool, error) {
+	unwindLinesStage := bson.D{
+		{Key: "$unwind", Value: "$lines"},
+	}
+
+	lookupIngredientStage := bson.D{
+		{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "ingredient"},
+			{Key: "localField", Value: "lines.ingredient_id"},
+			{Key: "foreignField", Value: "ingredient_id"},
+			{Key: "as", Value: "ingredient"},
+		}},
+	}
+
+	unwindIngredientStage := bson.D{
+		{Key: "$unwind", Value: "$ingredient"},
+	}
+
+	// one row per recipe, telling whether any of its ingredients ran out
+	groupStage := bson.D{
+		{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "food_id", Value: "$food_id"},
+				{Key: "size", Value: "$size"},
+			}},
+			{Key: "sold_out", Value: bson.D{{Key: "$max", Value: bson.D{
+				{Key: "$lte", Value: bson.A{"$ingredient.quantity_on_hand", 0}},
+			}}}},
+		}},
+	}
+
+	result, err := recipeCollection.Aggregate(ctx, mongo.Pipeline{
+		unwindLinesStage,
+		lookupIngredientStage,
+		unwindIngredientStage,
+		groupStage,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var recipes []struct {
+		ID struct {
+			Food_id string
+			Size    *string
+		} `bson:"_id"`
+		Sold_out bool
+	}
+
+	if err := result.All(ctx, &recipes); err != nil {
+		return nil, err
+	}
+
+	// recipe stock by food, then by size; "" is the recipe for every size
+	recipeSoldOut := map[string]map[string]bool{}
+
+	for _, recipe := range recipes {
+		size := ""
+
+		if recipe.ID.Size != nil {
+			size = *recipe.ID.Size
+		}
+
+		if recipeSoldOut[recipe.ID.Food_id] == nil {
+			recipeSoldOut[recipe.ID.Food_id] = map[string]bool{}
+		}
+
+		recipeSoldOut[recipe.ID.Food_id][size] = recipe.Sold_out
+	}
+
+	soldOut := map[string]map[string]bool{}
+
+	for foodId, bySize := range recipeSoldOut {
+		for _, size := range foodSizes {
+			out, ok := bySize[size]
+
+			if !ok {
+				out = bySize[""]
+			}
+
+			if !out {
+				continue
+			}
+
+			if soldOut[foodId] == nil {
+				soldOut[foodId] = map[string]bool{}
+			}
+
+			soldOut[foodId][size] = true
+		}
+	}
+
+	// a manual flag overrides the stock
+	overrideResult, err := foodCollection.Find(ctx, bson.M{"availability": bson.M{"$in": bson.A{"AVAILABLE", "UNAVAILABLE"}}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var overridden []models.Food
+
+	if err := overrideResult.All(ctx, &overridden); err != nil {
+		return nil, err
+	}
+
+	for _, food := range overridden {
+		delete(soldOut, food.Food_id)
+
+		if *food.Availability == "UNAVAILABLE" {
+			soldOut[food.Food_id] = map[string]bool{}
+
+			for _, size := range foodSizes {
+				soldOut[food.Food_id][size] = true
+			}
+		}
+	}
+
+	return soldOut, nil
+}
+
+// foods that are sold out in every size, which are hidden from the menus
+func unavailableFoodIds(ctx context.Context) ([]string, error) {
+	soldOut, err := soldOutSizes(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	foodIds := []string{}
+
+	for foodId, sizes := range soldOut {
+		if len(sizes) == len(foodSizes) {
+			foodIds = append(foodIds, foodId)
+		}
+	}
+
+	return foodIds, nil
+}
+
+// a food ordered in a size; without a size it only has to be left in one
+type orderedFood struct {
+	foodId string
+	size   *string
+}
+
+// rejects an order containing a food sold out in the ordered size, naming the dish
+func checkFoodsAvailable(ctx context.Context, orderedFoods []orderedFood) error {
+	soldOut, err := soldOutSizes(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for _, ordered := range orderedFoods {
+		sizes := soldOut[ordered.foodId]
+
+		if ordered.size != nil && !sizes[*ordered.size] {
+			continue
+		}
+
+		if ordered.size == nil && len(sizes) < len(foodSizes) {
+			continue
+		}
+
+		name := "food " + ordered.foodId
+		var food models.Food
+
+		if err := foodCollection.FindOne(ctx, bson.M{"food_id": ordered.foodId}).Decode(&food); err == nil {
+			name = *food.Name
+		}
+
+		if ordered.size != nil && len(sizes) < len(foodSizes) {
+			return errors.New(name + " is sold out in size " + *ordered.size)
+		}
+
+		return errors.New(name + " is sold out")
+	}
+
+	return nil
+}
+
 func round(num float64) int {
 	// copysign >>> return a value of x, with the sign(+/-) of y
 	return int(num + math.Copysign(0.5, num))
@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var ingredientCollection *mongo.Collection = database.OpenCollection(database.Client, "ingredient")
+
+func GetIngredients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := ingredientCollection.Find(ctx, bson.M{})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving ingredients from database"})
+			return
+		}
+
+		var allIngredients []bson.M
+
+		if err := result.All(ctx, &allIngredients); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allIngredients)
+	}
+}
+
+func GetIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ingredientId := c.Param("ingredient_id")
+		var ingredient models.Ingredient
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := ingredientCollection.FindOne(ctx, bson.M{"ingredient_id": ingredientId}).Decode(&ingredient)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ingredient not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, ingredient)
+	}
+}
+
+func CreateIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ingredient models.Ingredient
+
+		if err := c.BindJSON(&ingredient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(ingredient)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		var err error
+
+		ingredient.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+			return
+		}
+
+		ingredient.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		ingredient.ID = primitive.NewObjectID()
+		ingredient.Ingredient_id = ingredient.ID.Hex()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, insertErr := ingredientCollection.InsertOne(ctx, ingredient)
+		defer cancel()
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ingredient is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateIngredient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ingredient models.Ingredient
+
+		if err := c.BindJSON(&ingredient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ingredientId := c.Param("ingredient_id")
+		var updateObj primitive.D
+
+		if ingredient.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: ingredient.Name})
+		}
+
+		if ingredient.Unit != nil {
+			updateObj = append(updateObj, bson.E{Key: "unit", Value: ingredient.Unit})
+		}
+
+		// a stock count sets the on-hand quantity directly
+		if ingredient.Quantity_on_hand != nil {
+			updateObj = append(updateObj, bson.E{Key: "quantity_on_hand", Value: ingredient.Quantity_on_hand})
+		}
+
+		if ingredient.Low_stock_level != nil {
+			updateObj = append(updateObj, bson.E{Key: "low_stock_level", Value: ingredient.Low_stock_level})
+		}
+
+		if ingredient.Unit_cost != nil {
+			updateObj = append(updateObj, bson.E{Key: "unit_cost", Value: ingredient.Unit_cost})
+		}
+
+		if ingredient.Par_level != nil {
+			updateObj = append(updateObj, bson.E{Key: "par_level", Value: ingredient.Par_level})
+		}
+
+		if ingredient.Supplier_id != nil {
+			updateObj = append(updateObj, bson.E{Key: "supplier_id", Value: ingredient.Supplier_id})
+		}
+
+		var err error
+		ingredient.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: ingredient.Updated_at})
+
+		filter := bson.M{"ingredient_id": ingredientId}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := ingredientCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: updateObj},
+			},
+		)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "ingredient updated failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "ingredient not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func GetStockLevels() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		projectStage := bson.D{
+			{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "ingredient_id", Value: 1},
+				{Key: "name", Value: 1},
+				{Key: "unit", Value: 1},
+				{Key: "quantity_on_hand", Value: 1},
+				{Key: "low_stock_level", Value: 1},
+				{Key: "is_low", Value: bson.D{
+					{Key: "$lte", Value: bson.A{"$quantity_on_hand", bson.D{{Key: "$ifNull", Value: bson.A{"$low_stock_level", 0}}}}},
+				}},
+			}},
+		}
+
+		pipeline := mongo.Pipeline{projectStage}
+
+		if c.Query("low") == "true" {
+			pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: "is_low", Value: true}}}})
+		}
+
+		// lowest stock relative to its level first
+		sortStage := bson.D{
+			{Key: "$sort", Value: bson.D{
+				{Key: "is_low", Value: -1},
+				{Key: "quantity_on_hand", Value: 1},
+			}},
+		}
+
+		pipeline = append(pipeline, sortStage)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := ingredientCollection.Aggregate(ctx, pipeline)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while listing stock levels"})
+			return
+		}
+
+		var stockLevels []bson.M
+
+		if err := result.All(ctx, &stockLevels); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, stockLevels)
+	}
+}
+
+// finds the recipe for a food, preferring the one for the ordered size
+func recipeForFood(ctx context.Context, foodId string, size *string) (*models.Recipe, error) {
+	var recipe models.Recipe
+
+	if size != nil {
+		err := recipeCollection.FindOne(ctx, bson.M{"food_id": foodId, "size": *size}).Decode(&recipe)
+
+		if err == nil {
+			return &recipe, nil
+		}
+
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+	}
+
+	err := recipeCollection.FindOne(ctx, bson.M{"food_id": foodId, "size": nil}).Decode(&recipe)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &recipe, nil
+}
+
+// sums the ingredient amounts used by the order items, keyed by ingredient_id
+func ingredientUsage(ctx context.Context, orderItems []models.OrderItem) (map[string]float64, error) {
+	usage := map[string]float64{}
+
+	for _, orderItem := range orderItems {
+		if orderItem.Food_id == nil {
+			continue
+		}
+
+		recipe, err := recipeForFood(ctx, *orderItem.Food_id, orderItem.Quantity)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if recipe == nil {
+			continue
+		}
+
+		for _, line := range recipe.Lines {
+			usage[*line.Ingredient_id] += *line.Amount
+		}
+	}
+
+	return usage, nil
+}
+
+// deducts (direction -1) or returns (direction 1) the stock used by the order items
+func adjustStockForOrderItems(ctx context.Context, orderItems []models.OrderItem, direction float64) error {
+	usage, err := ingredientUsage(ctx, orderItems)
+
+	if err != nil {
+		return err
+	}
+
+	return adjustStock(ctx, usage, direction)
+}
+
+func adjustStock(ctx context.Context, usage map[string]float64, direction float64) error {
+	for ingredientId, amount := range usage {
+		_, err := ingredientCollection.UpdateOne(
+			ctx,
+			bson.M{"ingredient_id": ingredientId},
+			bson.D{
+				{Key: "$inc", Value: bson.D{{Key: "quantity_on_hand", Value: direction * amount}}},
+				{Key: "$set", Value: bson.D{{Key: "updated_at", Value: time.Now()}}},
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,716 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/models"
+	"log"
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// money is added up in cents so that rounded lines always match the totals
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+func fromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// the billable lines of an order with the menu category of each food
+func invoiceLinesForOrder(ctx context.Context, orderId string) ([]models.InvoiceLine, error) {
+	matchStage := bson.D{
+		{Key: "$match", Value: bson.D{
+			{Key: "order_id", Value: orderId},
+			{Key: "voided_at", Value: nil},
+		}},
+	}
+
+	lookupFoodStage := bson.D{
+		{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "food"},
+			{Key: "localField", Value: "food_id"},
+			{Key: "foreignField", Value: "food_id"},
+			{Key: "as", Value: "food"},
+		}},
+	}
+
+	unwindFoodStage := bson.D{
+		{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$food"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}},
+	}
+
+	lookupMenuStage := bson.D{
+		{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "menu"},
+			{Key: "localField", Value: "food.menu_id"},
+			{Key: "foreignField", Value: "menu_id"},
+			{Key: "as", Value: "menu"},
+		}},
+	}
+
+	unwindMenuStage := bson.D{
+		{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$menu"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}},
+	}
+
+	lookupComboStage := bson.D{
+		{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "combo"},
+			{Key: "localField", Value: "combo_id"},
+			{Key: "foreignField", Value: "combo_id"},
+			{Key: "as", Value: "combo"},
+		}},
+	}
+
+	unwindComboStage := bson.D{
+		{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$combo"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}},
+	}
+
+	projectStage := bson.D{
+		{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "order_item_id", Value: 1},
+			{Key: "parent_order_item_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$parent_order_item_id", ""}}}},
+			{Key: "seat_number", Value: 1},
+			{Key: "food_id", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$food_id", ""}}}},
+			{Key: "name", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$food.name", "$combo.name"}}}},
+			{Key: "category", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$menu.category", ""}}}},
+			{Key: "amount", Value: "$unit_price"},
+		}},
+	}
+
+	sortStage := bson.D{
+		{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}}},
+	}
+
+	result, err := orderItemCollection.Aggregate(ctx, mongo.Pipeline{
+		matchStage,
+		sortStage,
+		lookupFoodStage,
+		unwindFoodStage,
+		lookupMenuStage,
+		unwindMenuStage,
+		lookupComboStage,
+		unwindComboStage,
+		projectStage,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []models.InvoiceLine
+
+	if err := result.All(ctx, &lines); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+func discountAmount(discountType string, value float64, base int64) int64 {
+	amount := toCents(value)
+
+	if discountType == "PERCENTAGE" {
+		amount = int64(math.Round(float64(base) * value / 100))
+	}
+
+	if amount > base {
+		return base
+	}
+
+	return amount
+}
+
+// spreads an order discount over the lines in proportion to what is left on each,
+// so that every line is taxed on its discounted amount
+func allocateOrderDiscount(remaining []int64, discountType string, value float64) int64 {
+	var base int64
+
+	for _, amount := range remaining {
+		base += amount
+	}
+
+	discount := discountAmount(discountType, value, base)
+
+	if discount == 0 {
+		return 0
+	}
+
+	last := -1
+
+	for i, amount := range remaining {
+		if amount > 0 {
+			last = i
+		}
+	}
+
+	var allocated int64
+
+	for i, amount := range remaining {
+		if amount == 0 {
+			continue
+		}
+
+		share := discount - allocated
+
+		if i != last {
+			share = int64(math.Round(float64(discount) * float64(amount) / float64(base)))
+		}
+
+		if share > amount {
+			share = amount
+		}
+
+		remaining[i] -= share
+		allocated += share
+	}
+
+	return allocated
+}
+
+// applies line promotions first, then order promotions, then the manual discount.
+// For every entry added to invoice.Discounts it returns the cents taken off
+// each line, so that split bills can share the discounts of the whole order.
+func applyDiscounts(invoice *models.Invoice, promotions []models.Promotion, manual *models.ManualDiscount, userId string) [][]int64 {
+	remaining := make([]int64, len(invoice.Lines))
+	allocations := [][]int64{}
+	var gross int64
+
+	for i, line := range invoice.Lines {
+		remaining[i] = toCents(line.Amount)
+		gross += remaining[i]
+	}
+
+	invoice.Discounts = []models.InvoiceDiscount{}
+
+	for _, scope := range []string{"LINE", "ORDER"} {
+		for _, promotion := range promotions {
+			if *promotion.Scope != scope {
+				continue
+			}
+
+			if promotion.Min_subtotal != nil && gross < toCents(*promotion.Min_subtotal) {
+				continue
+			}
+
+			before := append([]int64{}, remaining...)
+			var amount int64
+
+			if scope == "LINE" {
+				for i, line := range invoice.Lines {
+					if !containsString(promotion.Food_ids, line.Food_id) {
+						continue
+					}
+
+					discount := discountAmount(*promotion.Type, *promotion.Value, remaining[i])
+					remaining[i] -= discount
+					amount += discount
+				}
+			} else {
+				amount = allocateOrderDiscount(remaining, *promotion.Type, *promotion.Value)
+			}
+
+			if amount == 0 {
+				continue
+			}
+
+			invoiceDiscount := models.InvoiceDiscount{
+				Promotion_id: promotion.Promotion_id,
+				Name:         *promotion.Name,
+				Type:         *promotion.Type,
+				Value:        *promotion.Value,
+				Scope:        scope,
+				Amount:       fromCents(amount),
+				Applied_by:   userId,
+			}
+
+			if promotion.Code != nil {
+				invoiceDiscount.Code = *promotion.Code
+			}
+
+			invoice.Discounts = append(invoice.Discounts, invoiceDiscount)
+			allocations = append(allocations, lineShares(before, remaining))
+		}
+	}
+
+	if manual != nil {
+		before := append([]int64{}, remaining...)
+		amount := allocateOrderDiscount(remaining, *manual.Type, *manual.Value)
+
+		if amount > 0 {
+			invoice.Discounts = append(invoice.Discounts, models.InvoiceDiscount{
+				Name:       "Manual discount",
+				Type:       *manual.Type,
+				Value:      *manual.Value,
+				Scope:      "ORDER",
+				Amount:     fromCents(amount),
+				Reason:     *manual.Reason,
+				Applied_by: userId,
+			})
+			allocations = append(allocations, lineShares(before, remaining))
+		}
+	}
+
+	var discountTotal int64
+
+	for i := range invoice.Lines {
+		discount := toCents(invoice.Lines[i].Amount) - remaining[i]
+		invoice.Lines[i].Discount = fromCents(discount)
+		discountTotal += discount
+	}
+
+	invoice.Discount_total = fromCents(discountTotal)
+
+	return allocations
+}
+
+func lineShares(before []int64, after []int64) []int64 {
+	shares := make([]int64, len(before))
+
+	for i := range before {
+		shares[i] = before[i] - after[i]
+	}
+
+	return shares
+}
+
+// prices each group of lines as its own invoice while discounting the order as
+// a whole: the promotions are applied once to all the lines, so a fixed amount
+// or a minimum subtotal counts for the order, and every discount is shared
+// among the groups by the lines it was taken off
+func priceLineGroups(ctx context.Context, groups [][]models.InvoiceLine, promotions []models.Promotion, order models.Order, userId string) ([]models.Invoice, error) {
+	full := models.Invoice{Lines: []models.InvoiceLine{}}
+	groupOf := []int{}
+
+	for i, group := range groups {
+		for _, line := range group {
+			full.Lines = append(full.Lines, line)
+			groupOf = append(groupOf, i)
+		}
+	}
+
+	allocations := applyDiscounts(&full, promotions, nil, userId)
+
+	invoices := make([]models.Invoice, len(groups))
+
+	for i := range invoices {
+		invoices[i].Lines = []models.InvoiceLine{}
+		invoices[i].Discounts = []models.InvoiceDiscount{}
+	}
+
+	for i, line := range full.Lines {
+		invoices[groupOf[i]].Lines = append(invoices[groupOf[i]].Lines, line)
+	}
+
+	for d, discount := range full.Discounts {
+		amounts := make([]int64, len(groups))
+
+		for i, share := range allocations[d] {
+			amounts[groupOf[i]] += share
+		}
+
+		for i, amount := range amounts {
+			if amount == 0 {
+				continue
+			}
+
+			invoiceDiscount := discount
+			invoiceDiscount.Amount = fromCents(amount)
+			invoices[i].Discounts = append(invoices[i].Discounts, invoiceDiscount)
+		}
+	}
+
+	for i := range invoices {
+		invoice := &invoices[i]
+		var discountTotal int64
+
+		for _, line := range invoice.Lines {
+			discountTotal += toCents(line.Discount)
+		}
+
+		invoice.Discount_total = fromCents(discountTotal)
+
+		if err := priceInvoice(ctx, invoice, order); err != nil {
+			return nil, err
+		}
+	}
+
+	return invoices, nil
+}
+
+func taxApplies(taxRate models.TaxRate, category string) bool {
+	if len(taxRate.Categories) == 0 {
+		return true
+	}
+
+	return containsString(taxRate.Categories, category)
+}
+
+// applies the tax rates to every line, rounding each line tax to the cent, and
+// adds the rounded line taxes up per rate so the breakdown matches the lines
+func applyTaxes(invoice *models.Invoice, taxRates []models.TaxRate) {
+	type taxTotal struct {
+		taxable int64
+		amount  int64
+	}
+
+	totals := map[string]*taxTotal{}
+	var subtotal, grandTotal int64
+
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		amount := toCents(line.Amount) - toCents(line.Discount)
+
+		var inclusiveRates, exclusiveRates []models.TaxRate
+		inclusivePercentage := 0.0
+
+		for _, taxRate := range taxRates {
+			if !taxApplies(taxRate, line.Category) {
+				continue
+			}
+
+			if *taxRate.Inclusive {
+				inclusiveRates = append(inclusiveRates, taxRate)
+				inclusivePercentage += *taxRate.Rate
+			} else {
+				exclusiveRates = append(exclusiveRates, taxRate)
+			}
+		}
+
+		// inclusive taxes are backed out of the price, the last rate takes the rounding remainder
+		net := int64(math.Round(float64(amount) / (1 + inclusivePercentage/100)))
+		inclusiveTax := amount - net
+		var allocated int64
+
+		line.Taxes = []models.LineTax{}
+
+		for j, taxRate := range inclusiveRates {
+			share := inclusiveTax - allocated
+
+			if j < len(inclusiveRates)-1 {
+				share = int64(math.Round(float64(inclusiveTax) * *taxRate.Rate / inclusivePercentage))
+			}
+
+			allocated += share
+			line.Taxes = append(line.Taxes, models.LineTax{Tax_rate_id: taxRate.Tax_rate_id, Amount: fromCents(share)})
+		}
+
+		// exclusive taxes are charged on top of the net amount
+		var exclusiveTax int64
+
+		for _, taxRate := range exclusiveRates {
+			tax := int64(math.Round(float64(net) * *taxRate.Rate / 100))
+			exclusiveTax += tax
+			line.Taxes = append(line.Taxes, models.LineTax{Tax_rate_id: taxRate.Tax_rate_id, Amount: fromCents(tax)})
+		}
+
+		for _, lineTax := range line.Taxes {
+			total, ok := totals[lineTax.Tax_rate_id]
+
+			if !ok {
+				total = &taxTotal{}
+				totals[lineTax.Tax_rate_id] = total
+			}
+
+			total.taxable += net
+			total.amount += toCents(lineTax.Amount)
+		}
+
+		line.Net_amount = fromCents(net)
+		line.Total = fromCents(amount + exclusiveTax)
+
+		subtotal += net
+		grandTotal += amount + exclusiveTax
+	}
+
+	invoice.Taxes = []models.InvoiceTax{}
+	var taxSum int64
+
+	for _, taxRate := range taxRates {
+		total, ok := totals[taxRate.Tax_rate_id]
+
+		if !ok {
+			continue
+		}
+
+		taxSum += total.amount
+		invoice.Taxes = append(invoice.Taxes, models.InvoiceTax{
+			Tax_rate_id:    taxRate.Tax_rate_id,
+			Name:           *taxRate.Name,
+			Rate:           *taxRate.Rate,
+			Inclusive:      *taxRate.Inclusive,
+			Taxable_amount: fromCents(total.taxable),
+			Amount:         fromCents(total.amount),
+		})
+	}
+
+	invoice.Subtotal = fromCents(subtotal)
+	invoice.Tax_total = fromCents(taxSum)
+	invoice.Total = fromCents(grandTotal)
+}
+
+func serviceChargeApplies(rule models.ServiceChargeRule, guests int, orderType string) bool {
+	if rule.Min_guests != nil && guests < *rule.Min_guests {
+		return false
+	}
+
+	if len(rule.Order_types) > 0 && !containsString(rule.Order_types, orderType) {
+		return false
+	}
+
+	return true
+}
+
+// adds the service charges of the matching rules, charged on the subtotal
+func applyServiceCharges(invoice *models.Invoice, rules []models.ServiceChargeRule, guests int, orderType string) {
+	subtotal := toCents(invoice.Subtotal)
+	total := toCents(invoice.Total)
+	var serviceCharge int64
+
+	invoice.Service_charges = []models.InvoiceServiceCharge{}
+
+	for _, rule := range rules {
+		if !serviceChargeApplies(rule, guests, orderType) {
+			continue
+		}
+
+		amount := int64(math.Round(float64(subtotal) * *rule.Rate / 100))
+		serviceCharge += amount
+
+		invoice.Service_charges = append(invoice.Service_charges, models.InvoiceServiceCharge{
+			Service_charge_rule_id: rule.Service_charge_rule_id,
+			Name:                   *rule.Name,
+			Rate:                   *rule.Rate,
+			Amount:                 fromCents(amount),
+		})
+	}
+
+	invoice.Service_charge = fromCents(serviceCharge)
+	invoice.Total = fromCents(total + serviceCharge)
+}
+
+// taxes the discounted lines and adds the service charges for the order's party
+func priceInvoice(ctx context.Context, invoice *models.Invoice, order models.Order) error {
+	taxRates, err := activeTaxRates(ctx)
+
+	if err != nil {
+		log.Println(err)
+		return errors.New("error occured while retrieving tax rates")
+	}
+
+	applyTaxes(invoice, taxRates)
+
+	var table models.Table
+
+	if err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_id}).Decode(&table); err != nil {
+		log.Println(err)
+		return errors.New("table not found")
+	}
+
+	serviceChargeRules, err := activeServiceChargeRules(ctx)
+
+	if err != nil {
+		log.Println(err)
+		return errors.New("error occured while retrieving service charge rules")
+	}
+
+	guests := 0
+
+	if table.Number_of_guest != nil {
+		guests = *table.Number_of_guest
+	}
+
+	orderType := "DINE_IN"
+
+	if order.Order_type != nil {
+		orderType = *order.Order_type
+	}
+
+	applyServiceCharges(invoice, serviceChargeRules, guests, orderType)
+
+	return nil
+}
+
+// groups the lines by the order items listed in each group; combo components
+// follow their bundle line and every other line has to be listed exactly once
+func groupLinesByItem(lines []models.InvoiceLine, itemGroups [][]string) ([][]models.InvoiceLine, error) {
+	groupOf := map[string]int{}
+
+	for i, itemGroup := range itemGroups {
+		if len(itemGroup) == 0 {
+			return nil, errors.New("item groups must not be empty")
+		}
+
+		for _, orderItemId := range itemGroup {
+			if _, ok := groupOf[orderItemId]; ok {
+				return nil, errors.New("order item " + orderItemId + " is assigned more than once")
+			}
+
+			groupOf[orderItemId] = i
+		}
+	}
+
+	groups := make([][]models.InvoiceLine, len(itemGroups))
+	assigned := 0
+
+	for _, line := range lines {
+		i, ok := groupOf[line.Order_item_id]
+
+		if ok {
+			assigned++
+		} else if line.Parent_order_item_id != "" {
+			i, ok = groupOf[line.Parent_order_item_id]
+		}
+
+		if !ok {
+			return nil, errors.New("order item " + line.Order_item_id + " is not assigned to any group")
+		}
+
+		groups[i] = append(groups[i], line)
+	}
+
+	if assigned != len(groupOf) {
+		return nil, errors.New("item groups contain order items that are not billable on this order")
+	}
+
+	return groups, nil
+}
+
+// groups the lines by seat number, combo components sit with their bundle line
+func groupLinesBySeat(lines []models.InvoiceLine) ([][]models.InvoiceLine, error) {
+	seatOf := map[string]int{}
+
+	for _, line := range lines {
+		if line.Seat_number != nil {
+			seatOf[line.Order_item_id] = *line.Seat_number
+		}
+	}
+
+	bySeat := map[int][]models.InvoiceLine{}
+	seats := []int{}
+
+	for _, line := range lines {
+		seat, ok := seatOf[line.Order_item_id]
+
+		if !ok && line.Parent_order_item_id != "" {
+			seat, ok = seatOf[line.Parent_order_item_id]
+		}
+
+		if !ok {
+			return nil, errors.New("order item " + line.Name + " has no seat number")
+		}
+
+		if _, ok := bySeat[seat]; !ok {
+			seats = append(seats, seat)
+		}
+
+		bySeat[seat] = append(bySeat[seat], line)
+	}
+
+	sort.Ints(seats)
+
+	groups := [][]models.InvoiceLine{}
+
+	for _, seat := range seats {
+		groups = append(groups, bySeat[seat])
+	}
+
+	return groups, nil
+}
+
+// splits an amount in cents into equal parts, the first parts take the remainder
+func splitCents(amount int64, parts int) []int64 {
+	shares := make([]int64, parts)
+	base := amount / int64(parts)
+	remainder := amount - base*int64(parts)
+
+	for i := range shares {
+		shares[i] = base
+
+		if int64(i) < remainder {
+			shares[i]++
+		}
+	}
+
+	return shares
+}
+
+// divides a priced invoice into equal shares; every amount is split on its own
+// so the shares of each tax, charge and discount add up to the original
+func splitInvoiceEvenly(full models.Invoice, parts int) []models.Invoice {
+	invoices := make([]models.Invoice, parts)
+
+	for i := range invoices {
+		invoices[i].Lines = []models.InvoiceLine{}
+		invoices[i].Taxes = []models.InvoiceTax{}
+		invoices[i].Service_charges = []models.InvoiceServiceCharge{}
+		invoices[i].Discounts = []models.InvoiceDiscount{}
+	}
+
+	for i, share := range splitCents(toCents(full.Subtotal), parts) {
+		invoices[i].Subtotal = fromCents(share)
+	}
+
+	for i, share := range splitCents(toCents(full.Discount_total), parts) {
+		invoices[i].Discount_total = fromCents(share)
+	}
+
+	for _, tax := range full.Taxes {
+		taxable := splitCents(toCents(tax.Taxable_amount), parts)
+
+		for i, share := range splitCents(toCents(tax.Amount), parts) {
+			invoiceTax := tax
+			invoiceTax.Taxable_amount = fromCents(taxable[i])
+			invoiceTax.Amount = fromCents(share)
+			invoices[i].Taxes = append(invoices[i].Taxes, invoiceTax)
+		}
+	}
+
+	for _, serviceCharge := range full.Service_charges {
+		for i, share := range splitCents(toCents(serviceCharge.Amount), parts) {
+			invoiceServiceCharge := serviceCharge
+			invoiceServiceCharge.Amount = fromCents(share)
+			invoices[i].Service_charges = append(invoices[i].Service_charges, invoiceServiceCharge)
+		}
+	}
+
+	for _, discount := range full.Discounts {
+		for i, share := range splitCents(toCents(discount.Amount), parts) {
+			invoiceDiscount := discount
+			invoiceDiscount.Amount = fromCents(share)
+			invoices[i].Discounts = append(invoices[i].Discounts, invoiceDiscount)
+		}
+	}
+
+	for i := range invoices {
+		invoice := &invoices[i]
+		var taxTotal, serviceCharge int64
+
+		for _, tax := range invoice.Taxes {
+			taxTotal += toCents(tax.Amount)
+		}
+
+		for _, charge := range invoice.Service_charges {
+			serviceCharge += toCents(charge.Amount)
+		}
+
+		invoice.Tax_total = fromCents(taxTotal)
+		invoice.Service_charge = fromCents(serviceCharge)
+		invoice.Total = fromCents(toCents(invoice.Subtotal) + taxTotal + serviceCharge)
+	}
+
+	return invoices
+}
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"go-restaurant-management/models"
+	"testing"
+)
+
+func testTaxRate(id string, rate float64, inclusive bool, categories ...string) models.TaxRate {
+	name := id
+	return models.TaxRate{
+		Tax_rate_id: id,
+		Name:        &name,
+		Rate:        &rate,
+		Inclusive:   &inclusive,
+		Categories:  categories,
+	}
+}
+
+func TestToCents(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   int64
+	}{
+		{0, 0},
+		{19.99, 1999},
+		{0.1 + 0.2, 30},
+		{2.675, 268},
+		{-2.5, -250},
+	}
+
+	for _, test := range tests {
+		if got := toCents(test.amount); got != test.want {
+			t.Errorf("toCents(%v) = %d, want %d", test.amount, got, test.want)
+		}
+
+		if got := fromCents(test.want); toCents(got) != test.want {
+			t.Errorf("fromCents(%d) = %v does not round trip", test.want, got)
+		}
+	}
+}
+
+func TestApplyTaxes(t *testing.T) {
+	type lineWant struct {
+		net   float64
+		taxes []float64
+		total float64
+	}
+
+	tests := []struct {
+		name      string
+		lines     []models.InvoiceLine
+		taxRates  []models.TaxRate
+		wantLines []lineWant
+		wantTaxes map[string]float64
+		subtotal  float64
+		taxTotal  float64
+		total     float64
+	}{
+		{
+			name:      "exclusive",
+			lines:     []models.InvoiceLine{{Amount: 10}},
+			taxRates:  []models.TaxRate{testTaxRate("sst", 6, false)},
+			wantLines: []lineWant{{net: 10, taxes: []float64{0.6}, total: 10.6}},
+			wantTaxes: map[string]float64{"sst": 0.6},
+			subtotal:  10,
+			taxTotal:  0.6,
+			total:     10.6,
+		},
+		{
+			name:      "inclusive",
+			lines:     []models.InvoiceLine{{Amount: 10.6}},
+			taxRates:  []models.TaxRate{testTaxRate("sst", 6, true)},
+			wantLines: []lineWant{{net: 10, taxes: []float64{0.6}, total: 10.6}},
+			wantTaxes: map[string]float64{"sst": 0.6},
+			subtotal:  10,
+			taxTotal:  0.6,
+			total:     10.6,
+		},
+		{
+			name:      "mixed inclusive and exclusive",
+			lines:     []models.InvoiceLine{{Amount: 10.6}},
+			taxRates:  []models.TaxRate{testTaxRate("sst", 6, true), testTaxRate("levy", 10, false)},
+			wantLines: []lineWant{{net: 10, taxes: []float64{0.6, 1}, total: 11.6}},
+			wantTaxes: map[string]float64{"sst": 0.6, "levy": 1},
+			subtotal:  10,
+			taxTotal:  1.6,
+			total:     11.6,
+		},
+		{
+			name:      "inclusive rates share the rounding remainder",
+			lines:     []models.InvoiceLine{{Amount: 1}},
+			taxRates:  []models.TaxRate{testTaxRate("a", 6, true), testTaxRate("b", 4, true)},
+			wantLines: []lineWant{{net: 0.91, taxes: []float64{0.05, 0.04}, total: 1}},
+			wantTaxes: map[string]float64{"a": 0.05, "b": 0.04},
+			subtotal:  0.91,
+			taxTotal:  0.09,
+			total:     1,
+		},
+		{
+			name:  "each line is rounded before the totals are added up",
+			lines: []models.InvoiceLine{{Amount: 0.25}, {Amount: 0.25}, {Amount: 0.25}},
+			taxRates: []models.TaxRate{
+				testTaxRate("sst", 6, false),
+			},
+			wantLines: []lineWant{
+				{net: 0.25, taxes: []float64{0.02}, total: 0.27},
+				{net: 0.25, taxes: []float64{0.02}, total: 0.27},
+				{net: 0.25, taxes: []float64{0.02}, total: 0.27},
+			},
+			wantTaxes: map[string]float64{"sst": 0.06},
+			subtotal:  0.75,
+			taxTotal:  0.06,
+			total:     0.81,
+		},
+		{
+			name: "discounts and categories",
+			lines: []models.InvoiceLine{
+				{Amount: 10, Discount: 2, Category: "food"},
+				{Amount: 5.3, Category: "drinks"},
+			},
+			taxRates: []models.TaxRate{
+				testTaxRate("sst", 6, true, "drinks"),
+				testTaxRate("levy", 10, false, "food"),
+			},
+			wantLines: []lineWant{
+				{net: 8, taxes: []float64{0.8}, total: 8.8},
+				{net: 5, taxes: []float64{0.3}, total: 5.3},
+			},
+			wantTaxes: map[string]float64{"sst": 0.3, "levy": 0.8},
+			subtotal:  13,
+			taxTotal:  1.1,
+			total:     14.1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			invoice := models.Invoice{Lines: test.lines}
+			applyTaxes(&invoice, test.taxRates)
+
+			var lineTotals int64
+
+			for i, want := range test.wantLines {
+				line := invoice.Lines[i]
+
+				if line.Net_amount != want.net || line.Total != want.total {
+					t.Errorf("line %d: net %v total %v, want net %v total %v", i, line.Net_amount, line.Total, want.net, want.total)
+				}
+
+				if len(line.Taxes) != len(want.taxes) {
+					t.Fatalf("line %d: %d taxes, want %d", i, len(line.Taxes), len(want.taxes))
+				}
+
+				for j, tax := range want.taxes {
+					if line.Taxes[j].Amount != tax {
+						t.Errorf("line %d tax %d: %v, want %v", i, j, line.Taxes[j].Amount, tax)
+					}
+				}
+
+				lineTotals += toCents(line.Total)
+			}
+
+			if len(invoice.Taxes) != len(test.wantTaxes) {
+				t.Fatalf("%d invoice taxes, want %d", len(invoice.Taxes), len(test.wantTaxes))
+			}
+
+			for _, tax := range invoice.Taxes {
+				if tax.Amount != test.wantTaxes[tax.Tax_rate_id] {
+					t.Errorf("tax %s: %v, want %v", tax.Tax_rate_id, tax.Amount, test.wantTaxes[tax.Tax_rate_id])
+				}
+			}
+
+			if invoice.Subtotal != test.subtotal || invoice.Tax_total != test.taxTotal || invoice.Total != test.total {
+				t.Errorf("subtotal %v tax %v total %v, want %v %v %v", invoice.Subtotal, invoice.Tax_total, invoice.Total, test.subtotal, test.taxTotal, test.total)
+			}
+
+			// the totals reconcile with the rounded lines
+			if lineTotals != toCents(invoice.Total) {
+				t.Errorf("lines add up to %d cents, invoice total is %v", lineTotals, invoice.Total)
+			}
+		})
+	}
+}
+
+func TestSplitCents(t *testing.T) {
+	tests := []struct {
+		amount int64
+		parts  int
+		want   []int64
+	}{
+		{900, 3, []int64{300, 300, 300}},
+		{1000, 3, []int64{334, 333, 333}},
+		{1001, 3, []int64{334, 334, 333}},
+		{2, 3, []int64{1, 1, 0}},
+		{0, 2, []int64{0, 0}},
+		{1999, 1, []int64{1999}},
+	}
+
+	for _, test := range tests {
+		got := splitCents(test.amount, test.parts)
+
+		if len(got) != len(test.want) {
+			t.Fatalf("splitCents(%d, %d) = %v, want %v", test.amount, test.parts, got, test.want)
+		}
+
+		var sum int64
+
+		for i := range got {
+			sum += got[i]
+
+			if got[i] != test.want[i] {
+				t.Errorf("splitCents(%d, %d) = %v, want %v", test.amount, test.parts, got, test.want)
+				break
+			}
+		}
+
+		if sum != test.amount {
+			t.Errorf("splitCents(%d, %d) adds up to %d", test.amount, test.parts, sum)
+		}
+	}
+}
+
+func TestSplitInvoiceEvenly(t *testing.T) {
+	full := models.Invoice{
+		Lines: []models.InvoiceLine{
+			{Amount: 10.6, Category: "food"},
+			{Amount: 5.3, Category: "drinks"},
+			{Amount: 1.07, Discount: 0.01, Category: "drinks"},
+		},
+	}
+
+	applyTaxes(&full, []models.TaxRate{testTaxRate("sst", 6, true), testTaxRate("levy", 10, false, "food")})
+	full.Service_charges = []models.InvoiceServiceCharge{{Name: "Service", Rate: 10, Amount: 1.61}}
+	full.Service_charge = 1.61
+	full.Total = fromCents(toCents(full.Total) + 161)
+	full.Discount_total = 0.01
+	full.Discounts = []models.InvoiceDiscount{{Name: "Rounding", Amount: 0.01}}
+
+	for _, parts := range []int{1, 2, 3, 7} {
+		invoices := splitInvoiceEvenly(full, parts)
+
+		if len(invoices) != parts {
+			t.Fatalf("%d parts: got %d invoices", parts, len(invoices))
+		}
+
+		var subtotal, taxTotal, serviceCharge, discountTotal, total int64
+		taxes := map[string]int64{}
+
+		for i, invoice := range invoices {
+			subtotal += toCents(invoice.Subtotal)
+			taxTotal += toCents(invoice.Tax_total)
+			serviceCharge += toCents(invoice.Service_charge)
+			discountTotal += toCents(invoice.Discount_total)
+			total += toCents(invoice.Total)
+
+			for _, tax := range invoice.Taxes {
+				taxes[tax.Tax_rate_id] += toCents(tax.Amount)
+			}
+
+			if toCents(invoice.Total) != toCents(invoice.Subtotal)+toCents(invoice.Tax_total)+toCents(invoice.Service_charge) {
+				t.Errorf("%d parts: share %d does not add up: %+v", parts, i, invoice)
+			}
+
+			// the first shares take the remainder, so no share is more than a cent apart
+			if i > 0 && toCents(invoices[i-1].Subtotal) < toCents(invoice.Subtotal) {
+				t.Errorf("%d parts: share %d is larger than the share before it", parts, i)
+			}
+		}
+
+		if subtotal != toCents(full.Subtotal) || taxTotal != toCents(full.Tax_total) || serviceCharge != toCents(full.Service_charge) || discountTotal != toCents(full.Discount_total) || total != toCents(full.Total) {
+			t.Errorf("%d parts: shares add up to subtotal %d tax %d service %d discount %d total %d, want %+v", parts, subtotal, taxTotal, serviceCharge, discountTotal, total, full)
+		}
+
+		for _, tax := range full.Taxes {
+			if taxes[tax.Tax_rate_id] != toCents(tax.Amount) {
+				t.Errorf("%d parts: %s shares add up to %d, want %v", parts, tax.Tax_rate_id, taxes[tax.Tax_rate_id], tax.Amount)
+			}
+		}
+	}
+}
@@ -2,12 +2,15 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"go-restaurant-management/database"
 	"go-restaurant-management/models"
 	"log"
 	"net/http"
 	"time"
 
+	helper "go-restaurant-management/helpers"
+
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -17,19 +20,54 @@ import (
 
 type InvoiceViewFormat struct {
 	Invoice_id       string
+	Invoice_number   string
 	Payment_method   string
 	Payment_status   *string
 	Payment_due      interface{}
 	Payment_due_date time.Time
+	Created_at       time.Time
 	Table_number     interface{}
 	Order_id         string
 	Order_details    interface{}
+	Lines            []models.InvoiceLine
+	Discounts        []models.InvoiceDiscount
+	Discount_total   float64
+	Subtotal         float64
+	Taxes            []models.InvoiceTax
+	Tax_total        float64
+	Service_charges  []models.InvoiceServiceCharge
+	Service_charge   float64
+	Total            float64
+	Tip              float64
+	Amount_paid      float64
+	Balance_due      float64
+	Amount_refunded  float64
+	Payments         []models.Payment
+	Refunds          []models.Refund
+	History          []models.InvoiceEvent
+	Server_id        string
+	Split_mode       string
+	Split_index      int
+	Split_count      int
+}
+
+type InvoiceSplit struct {
+	Order_id       *string    `json:"order_id" validate:"required"`
+	Mode           *string    `json:"mode" validate:"required,eq=ITEM|eq=SEAT|eq=EVEN"`
+	Parts          *int       `json:"parts" validate:"required_if=Mode EVEN,omitempty,gte=2,lte=50"`
+	Item_groups    [][]string `json:"item_groups" validate:"required_if=Mode ITEM"` // order_item_ids billed together
+	Payment_method *string    `json:"payment_method" validate:"omitempty,eq=CASH|eq=CARD"`
 }
 
 var invoiceCollection *mongo.Collection = database.OpenCollection(database.Client, "invoice")
 
 func GetInvoices() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if format := exportFormat(c); format != "" {
+			exportList(c, format, invoiceCollection, bson.M{}, "invoices", invoiceColumns)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
 		result, err := invoiceCollection.Find(ctx, bson.M{})
@@ -55,42 +93,112 @@ func GetInvoices() gin.HandlerFunc {
 func GetInvoice() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		invoiceId := c.Param("invoice_id")
-		var invoice models.Invoice
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-
-		err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
 		defer cancel()
 
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invoice not found"})
+		invoiceView, err := buildInvoiceView(ctx, invoiceId)
+
+		if err == errInvoiceNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		var invoiceView InvoiceViewFormat
-
-		allOrderItems, err := ItemsByOrder(invoice.Invoice_id)
-
 		if err != nil {
+			log.Println(err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
 
-		invoiceView.Order_id = invoice.Order_id
-		invoiceView.Invoice_id = invoice.Invoice_id
-		invoiceView.Payment_due_date = invoice.Payment_due_date
+		c.JSON(http.StatusOK, invoiceView)
+	}
+}
 
-		invoiceView.Payment_method = "null"
-		if invoice.Payment_method != nil {
-			invoiceView.Payment_method = *invoice.Payment_method
-		}
+var errInvoiceNotFound = errors.New("invoice not found")
 
-		invoiceView.Payment_status = invoice.Payment_status
-		invoiceView.Payment_due = allOrderItems[0]["payment_due"]
-		invoiceView.Table_number = allOrderItems[0]["table_number"]
-		invoiceView.Order_details = allOrderItems[0]["order_items"]
+// gathers an invoice with its order details, payments and refunds, as shown by
+// GetInvoice and printed on receipts
+func buildInvoiceView(ctx context.Context, invoiceId string) (InvoiceViewFormat, error) {
+	var invoice models.Invoice
+	var invoiceView InvoiceViewFormat
 
-		c.JSON(http.StatusOK, invoiceView)
+	if err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice); err != nil {
+		return invoiceView, errInvoiceNotFound
+	}
+
+	allOrderItems, err := ItemsByOrder(invoice.Order_id)
+
+	if err != nil {
+		return invoiceView, err
+	}
+
+	if len(allOrderItems) == 0 {
+		return invoiceView, errors.New("order items not found")
+	}
+
+	if err := legacyInvoiceTotal(&invoice); err != nil {
+		return invoiceView, err
+	}
+
+	invoiceView.Order_id = invoice.Order_id
+	invoiceView.Invoice_id = invoice.Invoice_id
+	invoiceView.Invoice_number = invoice.Invoice_number
+	invoiceView.Payment_due_date = invoice.Payment_due_date
+	invoiceView.Created_at = invoice.Created_at
+
+	invoiceView.Payment_method = "null"
+	if invoice.Payment_method != nil {
+		invoiceView.Payment_method = *invoice.Payment_method
+	}
+
+	invoiceView.Payment_status = invoice.Payment_status
+	invoiceView.Payment_due = invoice.Total
+	invoiceView.Table_number = allOrderItems[0]["table_number"]
+	invoiceView.Order_details = allOrderItems[0]["order_items"]
+	invoiceView.Lines = invoice.Lines
+	invoiceView.Discounts = invoice.Discounts
+	invoiceView.Discount_total = invoice.Discount_total
+	invoiceView.Subtotal = invoice.Subtotal
+	invoiceView.Taxes = invoice.Taxes
+	invoiceView.Tax_total = invoice.Tax_total
+	invoiceView.Total = invoice.Total
+	invoiceView.Service_charges = invoice.Service_charges
+	invoiceView.Service_charge = invoice.Service_charge
+	invoiceView.Server_id = invoice.Server_id
+	invoiceView.Split_mode = invoice.Split_mode
+	invoiceView.Split_index = invoice.Split_index
+	invoiceView.Split_count = invoice.Split_count
+
+	if invoice.Tip != nil {
+		invoiceView.Tip = *invoice.Tip
+	}
+
+	invoiceView.Amount_paid = invoice.Amount_paid
+	invoiceView.Balance_due = fromCents(toCents(invoice.Total) - toCents(invoice.Amount_paid))
+	invoiceView.Amount_refunded = invoice.Amount_refunded
+	invoiceView.History = invoice.History
+
+	paymentResult, err := paymentCollection.Find(ctx, bson.M{"invoice_id": invoice.Invoice_id})
+
+	if err != nil {
+		return invoiceView, err
+	}
+
+	if err := paymentResult.All(ctx, &invoiceView.Payments); err != nil {
+		return invoiceView, err
 	}
+
+	refundResult, err := refundCollection.Find(ctx, bson.M{"invoice_id": invoice.Invoice_id})
+
+	if err != nil {
+		return invoiceView, err
+	}
+
+	if err := refundResult.All(ctx, &invoiceView.Refunds); err != nil {
+		return invoiceView, err
+	}
+
+	return invoiceView, nil
 }
 
 func CreateInvoice() gin.HandlerFunc {
@@ -116,6 +224,60 @@ func CreateInvoice() gin.HandlerFunc {
 			return
 		}
 
+		invoice.Lines, err = invoiceLinesForOrder(ctx, order.Order_id)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving order items"})
+			return
+		}
+
+		// this invoice covers the whole order, split bills come from CreateSplitInvoices
+		invoice.Split_mode = ""
+		invoice.Split_index = 0
+		invoice.Split_count = 0
+
+		// manual discounts are for managers only and need a reason
+		if invoice.Manual_discount != nil {
+			if err := helper.CheckUserType(c, "MANAGER"); err != nil {
+				c.JSON(http.StatusForbidden, gin.H{"error": "only managers can give manual discounts"})
+				return
+			}
+
+			if validationError := validate.Struct(invoice.Manual_discount); validationError != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+				return
+			}
+		}
+
+		promotions, err := eligiblePromotions(ctx, invoice.Voucher_code)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		applyDiscounts(&invoice, promotions, invoice.Manual_discount, c.GetString("uid"))
+
+		if err := priceInvoice(ctx, &invoice, order); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		invoice.Server_id = order.Server_id
+
+		// invoices start unpaid, payments move them on
+		status := "PENDING"
+		invoice.Payment_status = &status
+		invoice.Amount_paid = 0
+		invoice.Amount_refunded = 0
+		invoice.History = []models.InvoiceEvent{}
+
+		// tips are added by the payments that bring them
+		tip := 0.0
+		invoice.Tip = &tip
+
 		// AddDate(years, months, days)
 		invoice.Payment_due_date, err = time.Parse(time.RFC3339, time.Now().AddDate(0, 0, 1).Format(time.RFC3339))
 
@@ -152,11 +314,54 @@ func CreateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		result, insertErr := invoiceCollection.InsertOne(ctx, invoice)
+		var result *mongo.InsertOneResult
+		var voucherErr error
+
+		// the vouchers, the invoice number and the invoice are taken together, so a
+		// failed insert neither uses a voucher up nor leaves a gap in the numbers
+		insertErr := database.Transaction(ctx, func(ctx context.Context) error {
+			voucherErr = nil
+
+			if err := claimOrderForInvoicing(ctx, order.Order_id); err != nil {
+				return err
+			}
+
+			for _, discount := range invoice.Discounts {
+				if discount.Code == "" {
+					continue
+				}
+
+				if voucherErr = redeemVoucher(ctx, discount.Promotion_id); voucherErr != nil {
+					return voucherErr
+				}
+			}
+
+			number, err := nextInvoiceNumber(ctx, invoice.Created_at)
+
+			if err != nil {
+				return err
+			}
+
+			invoice.Invoice_number = number
+
+			result, err = invoiceCollection.InsertOne(ctx, invoice)
+
+			return err
+		})
 		defer cancel()
 
+		if voucherErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": voucherErr.Error()})
+			return
+		}
+
+		if insertErr == errOrderInvoiced {
+			c.JSON(http.StatusBadRequest, gin.H{"error": insertErr.Error()})
+			return
+		}
+
 		if insertErr != nil {
-			log.Println(err)
+			log.Println(insertErr)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "invoice is not created due to some errors"})
 			return
 		}
@@ -165,6 +370,180 @@ func CreateInvoice() gin.HandlerFunc {
 	}
 }
 
+func CreateSplitInvoices() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var split InvoiceSplit
+		var order models.Order
+
+		if err := c.BindJSON(&split); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(split)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		err := orderCollection.FindOne(ctx, bson.M{"order_id": split.Order_id}).Decode(&order)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order not found"})
+			return
+		}
+
+		lines, err := invoiceLinesForOrder(ctx, order.Order_id)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving order items"})
+			return
+		}
+
+		if len(lines) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "this order has no billable items"})
+			return
+		}
+
+		// vouchers and manual discounts go on a single invoice, splits only get automatic promotions
+		promotions, err := eligiblePromotions(ctx, nil)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var invoices []models.Invoice
+
+		if *split.Mode == "EVEN" {
+			full := models.Invoice{Lines: lines}
+			applyDiscounts(&full, promotions, nil, c.GetString("uid"))
+
+			if err := priceInvoice(ctx, &full, order); err != nil {
+				log.Println(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			invoices = splitInvoiceEvenly(full, *split.Parts)
+		} else {
+			var groups [][]models.InvoiceLine
+
+			if *split.Mode == "ITEM" {
+				groups, err = groupLinesByItem(lines, split.Item_groups)
+			} else {
+				groups, err = groupLinesBySeat(lines)
+			}
+
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			invoices, err = priceLineGroups(ctx, groups, promotions, order, c.GetString("uid"))
+
+			if err != nil {
+				log.Println(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		paymentMethod := ""
+
+		if split.Payment_method != nil {
+			paymentMethod = *split.Payment_method
+		}
+
+		invoicesToBeInserted := []interface{}{}
+
+		for i := range invoices {
+			invoice := invoices[i]
+			status := "PENDING"
+			method := paymentMethod
+
+			invoice.Order_id = order.Order_id
+			invoice.Payment_method = &method
+			invoice.Payment_status = &status
+			invoice.Server_id = order.Server_id
+			invoice.Split_mode = *split.Mode
+			invoice.Split_index = i + 1
+			invoice.Split_count = len(invoices)
+			invoice.History = []models.InvoiceEvent{}
+
+			invoice.Payment_due_date, err = time.Parse(time.RFC3339, time.Now().AddDate(0, 0, 1).Format(time.RFC3339))
+
+			if err != nil {
+				log.Println(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing payment_due_date"})
+				return
+			}
+
+			invoice.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+			if err != nil {
+				log.Println(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+				return
+			}
+
+			invoice.Updated_at = invoice.Created_at
+			invoice.ID = primitive.NewObjectID()
+			invoice.Invoice_id = invoice.ID.Hex()
+
+			invoicesToBeInserted = append(invoicesToBeInserted, invoice)
+		}
+
+		var result *mongo.InsertManyResult
+
+		// numbered in the same transaction as the insert, so the sequence has no gaps
+		insertErr := database.Transaction(ctx, func(ctx context.Context) error {
+			if err := claimOrderForInvoicing(ctx, order.Order_id); err != nil {
+				return err
+			}
+
+			for i := range invoicesToBeInserted {
+				invoice := invoicesToBeInserted[i].(models.Invoice)
+
+				number, err := nextInvoiceNumber(ctx, invoice.Created_at)
+
+				if err != nil {
+					return err
+				}
+
+				invoice.Invoice_number = number
+				invoicesToBeInserted[i] = invoice
+			}
+
+			var err error
+			result, err = invoiceCollection.InsertMany(ctx, invoicesToBeInserted)
+
+			return err
+		})
+
+		if insertErr == errOrderInvoiced {
+			c.JSON(http.StatusBadRequest, gin.H{"error": insertErr.Error()})
+			return
+		}
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "invoices are not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
 func UpdateInvoice() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var invoice models.Invoice
@@ -182,8 +561,16 @@ func UpdateInvoice() gin.HandlerFunc {
 			updateObj = append(updateObj, bson.E{Key: "payment_method", Value: invoice.Payment_method})
 		}
 
+		// the status follows the payments recorded against the invoice
 		if invoice.Payment_status != nil {
-			updateObj = append(updateObj, bson.E{Key: "payment_status", Value: invoice.Payment_status})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "payment status cannot be changed directly, record a payment instead"})
+			return
+		}
+
+		// the tip is the sum of the tips recorded with the payments
+		if invoice.Tip != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tip cannot be changed directly, record it with a payment instead"})
+			return
 		}
 
 		// // if payment status is nil, update the status to "PENDING"
@@ -231,3 +618,344 @@ func UpdateInvoice() gin.HandlerFunc {
 		c.JSON(http.StatusOK, result)
 	}
 }
+
+// invoices created before the tax engine keep no lines and no total; they owe
+// the price of their order's items, which is taken as their total. The total
+// is stored with the first payment.
+func legacyInvoiceTotal(invoice *models.Invoice) error {
+	if invoice.Lines != nil || invoice.Split_count > 0 || invoice.Total != 0 {
+		return nil
+	}
+
+	allOrderItems, err := ItemsByOrder(invoice.Order_id)
+
+	if err != nil {
+		return err
+	}
+
+	if len(allOrderItems) == 0 {
+		return nil
+	}
+
+	var due float64
+
+	switch value := allOrderItems[0]["payment_due"].(type) {
+	case float64:
+		due = value
+	case int32:
+		due = float64(value)
+	case int64:
+		due = float64(value)
+	}
+
+	invoice.Total = toFixed(due, 2)
+	invoice.Subtotal = invoice.Total
+
+	return nil
+}
+
+var errInvoicePaid = errors.New("order has been paid, refund the payment instead")
+
+// takes the voided order items off the order's unpaid invoices and prices them
+// again with the discounts they were given, recording event in their history.
+// A split bill is priced again as a whole, so the order's discounts are shared
+// anew. Fails with errInvoicePaid when an invoice is paid, or would owe less
+// than has been paid on it; that money goes back through a refund instead.
+func repriceVoidedInvoices(ctx context.Context, orderId string, voided map[string]bool, event models.InvoiceEvent) error {
+	result, err := invoiceCollection.Find(ctx, bson.M{"order_id": orderId}, options.Find().SetSort(bson.D{{Key: "split_index", Value: 1}}))
+
+	if err != nil {
+		return err
+	}
+
+	var invoices []models.Invoice
+
+	if err := result.All(ctx, &invoices); err != nil {
+		return err
+	}
+
+	if len(invoices) == 0 {
+		return nil
+	}
+
+	for _, invoice := range invoices {
+		if invoice.Payment_status != nil && *invoice.Payment_status != "PENDING" && *invoice.Payment_status != "PARTIALLY_PAID" {
+			return errInvoicePaid
+		}
+	}
+
+	var order models.Order
+
+	if err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order); err != nil {
+		return err
+	}
+
+	// every invoice of an order was given the same promotions
+	promotions, manual, err := invoicePromotions(ctx, invoices[0])
+
+	if err != nil {
+		return err
+	}
+
+	remainingLines := func(invoice models.Invoice) []models.InvoiceLine {
+		lines := []models.InvoiceLine{}
+
+		for _, line := range invoice.Lines {
+			if !voided[line.Order_item_id] && !voided[line.Parent_order_item_id] {
+				lines = append(lines, line)
+			}
+		}
+
+		return lines
+	}
+
+	var repriced []models.Invoice
+
+	switch invoices[0].Split_mode {
+	case "":
+		invoice := models.Invoice{Lines: remainingLines(invoices[0])}
+
+		// invoices from before the tax engine keep no lines and bill the whole order
+		if invoices[0].Lines == nil {
+			invoice.Lines, err = invoiceLinesForOrder(ctx, orderId)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		applyDiscounts(&invoice, promotions, manual, event.User_id)
+
+		if err := priceInvoice(ctx, &invoice, order); err != nil {
+			return err
+		}
+
+		repriced = []models.Invoice{invoice}
+	case "EVEN":
+		// even shares keep no lines, the order's remaining items are shared again
+		lines, err := invoiceLinesForOrder(ctx, orderId)
+
+		if err != nil {
+			return err
+		}
+
+		full := models.Invoice{Lines: lines}
+		applyDiscounts(&full, promotions, nil, event.User_id)
+
+		if err := priceInvoice(ctx, &full, order); err != nil {
+			return err
+		}
+
+		repriced = splitInvoiceEvenly(full, len(invoices))
+	default:
+		groups := [][]models.InvoiceLine{}
+
+		for _, invoice := range invoices {
+			groups = append(groups, remainingLines(invoice))
+		}
+
+		repriced, err = priceLineGroups(ctx, groups, promotions, order, event.User_id)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	paidInFull := false
+
+	for i, invoice := range invoices {
+		price := repriced[i]
+		paid := toCents(invoice.Amount_paid)
+
+		if toCents(price.Total) < paid {
+			return errInvoicePaid
+		}
+
+		status := "PENDING"
+
+		if paid > 0 {
+			status = "PARTIALLY_PAID"
+		}
+
+		// a bill left with nothing to pay is settled
+		if toCents(price.Total) == paid {
+			status = "PAID"
+			paidInFull = true
+		}
+
+		filter := bson.M{
+			"invoice_id":     invoice.Invoice_id,
+			"payment_status": bson.M{"$in": bson.A{"PENDING", "PARTIALLY_PAID"}},
+			"amount_paid":    invoice.Amount_paid,
+		}
+
+		if invoice.Amount_paid == 0 {
+			filter["amount_paid"] = bson.M{"$in": bson.A{0, nil}}
+		}
+
+		updated, err := invoiceCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "lines", Value: price.Lines},
+					{Key: "discounts", Value: price.Discounts},
+					{Key: "discount_total", Value: price.Discount_total},
+					{Key: "subtotal", Value: price.Subtotal},
+					{Key: "taxes", Value: price.Taxes},
+					{Key: "tax_total", Value: price.Tax_total},
+					{Key: "service_charges", Value: price.Service_charges},
+					{Key: "service_charge", Value: price.Service_charge},
+					{Key: "total", Value: price.Total},
+					{Key: "payment_status", Value: status},
+					{Key: "updated_at", Value: event.Created_at},
+				}},
+				{Key: "$push", Value: bson.D{{Key: "history", Value: event}}},
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if updated.MatchedCount == 0 {
+			return errInvoicePaid
+		}
+	}
+
+	if paidInFull {
+		return closeOrderIfPaid(ctx, invoices[0].Invoice_id)
+	}
+
+	return nil
+}
+
+// the promotions and the manual discount an invoice was priced with, so that it
+// can be priced again; vouchers already redeemed still apply
+func invoicePromotions(ctx context.Context, invoice models.Invoice) ([]models.Promotion, *models.ManualDiscount, error) {
+	promotionIds := []string{}
+	var manual *models.ManualDiscount
+
+	for _, discount := range invoice.Discounts {
+		if discount.Promotion_id != "" {
+			promotionIds = append(promotionIds, discount.Promotion_id)
+			continue
+		}
+
+		discountType := discount.Type
+		value := discount.Value
+		reason := discount.Reason
+		manual = &models.ManualDiscount{Type: &discountType, Value: &value, Reason: &reason}
+	}
+
+	if len(promotionIds) == 0 {
+		return []models.Promotion{}, manual, nil
+	}
+
+	result, err := promotionCollection.Find(ctx, bson.M{"promotion_id": bson.M{"$in": promotionIds}})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var found []models.Promotion
+
+	if err := result.All(ctx, &found); err != nil {
+		return nil, nil, err
+	}
+
+	byId := map[string]models.Promotion{}
+
+	for _, promotion := range found {
+		byId[promotion.Promotion_id] = promotion
+	}
+
+	// in the order they were applied, a promotion deleted since is dropped
+	promotions := []models.Promotion{}
+
+	for _, promotionId := range promotionIds {
+		if promotion, ok := byId[promotionId]; ok {
+			promotions = append(promotions, promotion)
+		}
+	}
+
+	return promotions, manual, nil
+}
+
+var errOrderInvoiced = errors.New("this order already has invoices")
+
+// fails when the order already has invoices, whole or split. Run inside the
+// transaction that inserts the invoices: touching the order makes two
+// transactions invoicing the same order conflict, so the one retried after the
+// other committed sees its invoices.
+func claimOrderForInvoicing(ctx context.Context, orderId string) error {
+	invoiced_at, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = orderCollection.UpdateOne(
+		ctx,
+		bson.M{"order_id": orderId},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "updated_at", Value: invoiced_at}}}},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	count, err := invoiceCollection.CountDocuments(ctx, bson.M{"order_id": orderId})
+
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return errOrderInvoiced
+	}
+
+	return nil
+}
+
+// closes the invoice's order once every invoice of the order is paid
+func closeOrderIfPaid(ctx context.Context, invoiceId string) error {
+	var invoice models.Invoice
+
+	if err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice); err != nil {
+		return err
+	}
+
+	unpaid, err := invoiceCollection.CountDocuments(ctx, bson.M{
+		"order_id":       invoice.Order_id,
+		"payment_status": bson.M{"$ne": "PAID"},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if unpaid > 0 {
+		return nil
+	}
+
+	closed_at, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = orderCollection.UpdateOne(
+		ctx,
+		bson.M{"order_id": invoice.Order_id},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "status", Value: "CLOSED"},
+				{Key: "closed_at", Value: closed_at},
+				{Key: "updated_at", Value: closed_at},
+			}},
+		},
+	)
+
+	return err
+}
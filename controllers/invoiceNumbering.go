@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-restaurant-management/database"
+	"strconv"
+	"strings"
+	"time"
+
+	helper "go-restaurant-management/helpers"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type counter struct {
+	ID  string `bson:"_id"`
+	Seq int64  `bson:"seq"`
+}
+
+var counterCollection *mongo.Collection = database.OpenCollection(database.Client, "counter")
+
+type invoiceNumbering struct {
+	format string
+	digits int
+	reset  string
+	outlet string
+}
+
+// checks that the numbering settings can never hand out a number twice: a
+// sequence that restarts needs the period in the format, and outlets sharing
+// the unique invoice numbers need their id in it
+func parseInvoiceNumbering(format string, digits string, reset string, outlet string) (invoiceNumbering, error) {
+	numbering := invoiceNumbering{format: format, digits: 6, reset: reset, outlet: outlet}
+
+	if numbering.format == "" {
+		numbering.format = "INV-{year}-{seq}"
+	}
+
+	if numbering.reset == "" {
+		numbering.reset = "YEAR"
+	}
+
+	if !strings.Contains(numbering.format, "{seq}") {
+		return numbering, errors.New("INVOICE_NUMBER_FORMAT must contain {seq}")
+	}
+
+	if digits != "" {
+		parsed, err := strconv.Atoi(digits)
+
+		if err != nil || parsed < 1 {
+			return numbering, errors.New("INVOICE_NUMBER_DIGITS must be a positive number")
+		}
+
+		numbering.digits = parsed
+	}
+
+	switch numbering.reset {
+	case "YEAR":
+	case "MONTH":
+		if !strings.Contains(numbering.format, "{month}") {
+			return numbering, errors.New("INVOICE_NUMBER_FORMAT must contain {month} when INVOICE_NUMBER_RESET is MONTH")
+		}
+	case "NEVER":
+	default:
+		return numbering, errors.New("INVOICE_NUMBER_RESET must be YEAR, MONTH or NEVER")
+	}
+
+	if numbering.reset != "NEVER" && !strings.Contains(numbering.format, "{year}") {
+		return numbering, fmt.Errorf("INVOICE_NUMBER_FORMAT must contain {year} when INVOICE_NUMBER_RESET is %s", numbering.reset)
+	}
+
+	if numbering.outlet != "" && !strings.Contains(numbering.format, "{outlet}") {
+		return numbering, errors.New("INVOICE_NUMBER_FORMAT must contain {outlet} when OUTLET_ID is set")
+	}
+
+	return numbering, nil
+}
+
+func invoiceNumberingSettings() (invoiceNumbering, error) {
+	return parseInvoiceNumbering(
+		helper.GetEnvVariable("INVOICE_NUMBER_FORMAT"),
+		helper.GetEnvVariable("INVOICE_NUMBER_DIGITS"),
+		helper.GetEnvVariable("INVOICE_NUMBER_RESET"),
+		helper.GetEnvVariable("OUTLET_ID"),
+	)
+}
+
+// CheckInvoiceNumbering rejects invoice numbering settings that would repeat
+// numbers, so the server refuses to start instead of failing on invoices
+func CheckInvoiceNumbering() error {
+	_, err := invoiceNumberingSettings()
+	return err
+}
+
+// returns the next invoice number, for example INV-2026-000123. The layout comes
+// from INVOICE_NUMBER_FORMAT with the placeholders {year}, {month}, {outlet} and
+// {seq}, padded to INVOICE_NUMBER_DIGITS. INVOICE_NUMBER_RESET restarts the
+// sequence every YEAR (default), MONTH or NEVER, and every OUTLET_ID keeps its
+// own sequence. The counter is incremented atomically, so concurrent invoices
+// never share a number.
+func nextInvoiceNumber(ctx context.Context, issuedAt time.Time) (string, error) {
+	numbering, err := invoiceNumberingSettings()
+
+	if err != nil {
+		return "", err
+	}
+
+	var next counter
+
+	err = counterCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": "invoice:" + numbering.outlet + ":" + numbering.period(issuedAt)},
+		bson.D{{Key: "$inc", Value: bson.D{{Key: "seq", Value: 1}}}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&next)
+
+	if err != nil {
+		return "", err
+	}
+
+	return numbering.number(issuedAt, next.Seq), nil
+}
+
+// the period the sequence counts in
+func (numbering invoiceNumbering) period(issuedAt time.Time) string {
+	switch numbering.reset {
+	case "MONTH":
+		return issuedAt.Format("2006-01")
+	case "NEVER":
+		return "all"
+	}
+
+	return issuedAt.Format("2006")
+}
+
+func (numbering invoiceNumbering) number(issuedAt time.Time, seq int64) string {
+	return strings.NewReplacer(
+		"{year}", issuedAt.Format("2006"),
+		"{month}", issuedAt.Format("01"),
+		"{outlet}", numbering.outlet,
+		"{seq}", fmt.Sprintf("%0*d", numbering.digits, seq),
+	).Replace(numbering.format)
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseInvoiceNumbering(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		digits  string
+		reset   string
+		outlet  string
+		wantErr bool
+	}{
+		{name: "defaults"},
+		{name: "monthly with the month", format: "INV-{year}{month}-{seq}", reset: "MONTH"},
+		{name: "never resets", format: "{seq}", reset: "NEVER"},
+		{name: "outlet in the format", format: "{outlet}-{year}-{seq}", outlet: "KL1"},
+		{name: "no sequence", format: "INV-{year}", wantErr: true},
+		{name: "monthly without the month", reset: "MONTH", wantErr: true},
+		{name: "monthly without the year", format: "INV-{month}-{seq}", reset: "MONTH", wantErr: true},
+		{name: "yearly without the year", format: "INV-{seq}", reset: "YEAR", wantErr: true},
+		{name: "outlet missing from the format", outlet: "KL1", wantErr: true},
+		{name: "unknown reset", format: "{year}-{seq}", reset: "WEEK", wantErr: true},
+		{name: "bad digits", digits: "zero", wantErr: true},
+		{name: "no digits", digits: "0", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseInvoiceNumbering(test.format, test.digits, test.reset, test.outlet)
+
+			if (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvoiceNumber(t *testing.T) {
+	issuedAt := time.Date(2026, time.March, 5, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		format     string
+		digits     string
+		reset      string
+		outlet     string
+		wantPeriod string
+		wantNumber string
+	}{
+		{"", "", "", "", "2026", "INV-2026-000042"},
+		{"INV-{year}{month}-{seq}", "4", "MONTH", "", "2026-03", "INV-202603-0042"},
+		{"{outlet}/{year}/{seq}", "", "", "KL1", "2026", "KL1/2026/000042"},
+		{"R{seq}", "2", "NEVER", "", "all", "R42"},
+	}
+
+	for _, test := range tests {
+		numbering, err := parseInvoiceNumbering(test.format, test.digits, test.reset, test.outlet)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if period := numbering.period(issuedAt); period != test.wantPeriod {
+			t.Errorf("%q: period %q, want %q", test.format, period, test.wantPeriod)
+		}
+
+		if number := numbering.number(issuedAt, 42); number != test.wantNumber {
+			t.Errorf("%q: number %q, want %q", test.format, number, test.wantNumber)
+		}
+	}
+}
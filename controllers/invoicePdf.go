@@ -0,0 +1,392 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"go-restaurant-management/pdf"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	helper "go-restaurant-management/helpers"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type receiptRow struct {
+	Left     string
+	Right    string
+	Bold     bool
+	Centered bool
+	Rule     bool    // draws a separator instead of text
+	Scale    float64 // relative to the receipt's font size, 1 when zero
+}
+
+type receiptLine struct {
+	Name                 string
+	Amount               float64
+	Discount             float64
+	Parent_order_item_id string
+}
+
+// layout of printed receipts, read from the environment:
+// RECEIPT_PAPER is ROLL (80mm, default) or A4, RESTAURANT_NAME, RESTAURANT_ADDRESS
+// and RECEIPT_FOOTER are printed as is, with '|' starting a new line, and
+// RECEIPT_FONT_SIZE overrides the paper's default size
+type receiptLayout struct {
+	Width     float64
+	Height    float64 // 0 for a roll, which is as long as its content
+	Margin    float64
+	Font_size float64
+	Name      string
+	Address   []string
+	Footer    []string
+}
+
+func GetInvoicePDF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invoiceId := c.Param("invoice_id")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceView, err := buildInvoiceView(ctx, invoiceId)
+
+		if err == errInvoiceNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		layout, err := receiptLayoutFromEnv()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		filename := invoiceView.Invoice_number
+
+		if filename == "" {
+			filename = invoiceView.Invoice_id
+		}
+
+		c.Header("Content-Disposition", "inline; filename=\""+filename+".pdf\"")
+		c.Data(http.StatusOK, "application/pdf", renderReceipt(layout, receiptRows(layout, invoiceView)))
+	}
+}
+
+func receiptLayoutFromEnv() (receiptLayout, error) {
+	layout := receiptLayout{Width: 226.77, Margin: 10, Font_size: 8}
+
+	switch helper.GetEnvVariable("RECEIPT_PAPER") {
+	case "", "ROLL":
+	case "A4":
+		layout = receiptLayout{Width: 595.28, Height: 841.89, Margin: 48, Font_size: 10}
+	default:
+		return layout, fmt.Errorf("RECEIPT_PAPER must be ROLL or A4")
+	}
+
+	if value := helper.GetEnvVariable("RECEIPT_FONT_SIZE"); value != "" {
+		size, err := strconv.ParseFloat(value, 64)
+
+		if err != nil || size < 4 || size > 24 {
+			return layout, fmt.Errorf("RECEIPT_FONT_SIZE must be a number between 4 and 24")
+		}
+
+		layout.Font_size = size
+	}
+
+	layout.Name = helper.GetEnvVariable("RESTAURANT_NAME")
+	layout.Address = splitReceiptText(helper.GetEnvVariable("RESTAURANT_ADDRESS"))
+	layout.Footer = splitReceiptText(helper.GetEnvVariable("RECEIPT_FOOTER"))
+
+	if layout.Footer == nil {
+		layout.Footer = []string{"Thank you for your visit"}
+	}
+
+	return layout, nil
+}
+
+// lays out the receipt from top to bottom: header, itemized lines, totals,
+// payments and footer
+func receiptRows(layout receiptLayout, view InvoiceViewFormat) []receiptRow {
+	var rows []receiptRow
+
+	if layout.Name != "" {
+		rows = append(rows, receiptRow{Left: layout.Name, Bold: true, Centered: true, Scale: 1.5})
+	}
+
+	for _, line := range layout.Address {
+		rows = append(rows, receiptRow{Left: line, Centered: true})
+	}
+
+	rows = append(rows, receiptRow{Rule: true})
+
+	if view.Invoice_number != "" {
+		rows = append(rows, receiptRow{Left: "Invoice", Right: view.Invoice_number})
+	} else {
+		rows = append(rows, receiptRow{Left: "Invoice", Right: view.Invoice_id})
+	}
+
+	if !view.Created_at.IsZero() {
+		rows = append(rows, receiptRow{Left: "Date", Right: view.Created_at.Format("2006-01-02 15:04")})
+	}
+
+	if view.Table_number != nil {
+		rows = append(rows, receiptRow{Left: "Table", Right: fmt.Sprint(view.Table_number)})
+	}
+
+	if view.Split_count > 0 {
+		rows = append(rows, receiptRow{Left: "Bill", Right: fmt.Sprintf("%d of %d", view.Split_index, view.Split_count)})
+	}
+
+	rows = append(rows, receiptRow{Rule: true})
+
+	for _, line := range receiptLines(view) {
+		if line.Parent_order_item_id != "" {
+			// combo choices are included in the bundle's price
+			rows = append(rows, receiptRow{Left: "  " + line.Name})
+			continue
+		}
+
+		rows = append(rows, receiptRow{Left: line.Name, Right: receiptMoney(line.Amount)})
+
+		if line.Discount > 0 {
+			rows = append(rows, receiptRow{Left: "  Discount", Right: receiptMoney(-line.Discount)})
+		}
+	}
+
+	rows = append(rows, receiptRow{Rule: true})
+
+	if view.Lines != nil {
+		// every line shows its whole discount, its share of the order discounts
+		// included; bills split evenly have no lines and show their share of
+		// each discount instead
+		if len(view.Lines) == 0 {
+			for _, discount := range view.Discounts {
+				rows = append(rows, receiptRow{Left: discount.Name, Right: receiptMoney(-discount.Amount)})
+			}
+		}
+
+		rows = append(rows, receiptRow{Left: "Subtotal", Right: receiptMoney(view.Subtotal)})
+
+		for _, tax := range view.Taxes {
+			name := fmt.Sprintf("%s %s%%", tax.Name, strconv.FormatFloat(tax.Rate, 'f', -1, 64))
+
+			if tax.Inclusive {
+				name += " incl."
+			}
+
+			rows = append(rows, receiptRow{Left: name, Right: receiptMoney(tax.Amount)})
+		}
+
+		for _, charge := range view.Service_charges {
+			rows = append(rows, receiptRow{Left: charge.Name, Right: receiptMoney(charge.Amount)})
+		}
+	}
+
+	total := view.Total
+
+	if view.Lines == nil && view.Split_count == 0 {
+		if due, ok := view.Payment_due.(float64); ok {
+			total = due
+		}
+	}
+
+	rows = append(rows, receiptRow{Left: "TOTAL", Right: receiptMoney(total), Bold: true, Scale: 1.25})
+
+	if view.Tip > 0 {
+		rows = append(rows, receiptRow{Left: "Tip", Right: receiptMoney(view.Tip)})
+	}
+
+	if len(view.Payments) > 0 || len(view.Refunds) > 0 {
+		rows = append(rows, receiptRow{Rule: true})
+	}
+
+	for _, payment := range view.Payments {
+		if payment.Status != "" && payment.Status != "COMPLETED" {
+			continue
+		}
+
+		paid := *payment.Amount
+
+		if payment.Tip != nil {
+			paid += *payment.Tip
+		}
+
+		rows = append(rows, receiptRow{Left: receiptMethod(*payment.Method), Right: receiptMoney(paid)})
+
+		if payment.Tendered != nil {
+			rows = append(rows, receiptRow{Left: "  Tendered", Right: receiptMoney(*payment.Tendered)})
+			rows = append(rows, receiptRow{Left: "  Change", Right: receiptMoney(payment.Change_given)})
+		}
+	}
+
+	for _, refund := range view.Refunds {
+		rows = append(rows, receiptRow{Left: "Refund " + strings.ToLower(receiptMethod(refund.Method)), Right: receiptMoney(-*refund.Amount)})
+	}
+
+	if view.Lines != nil || view.Split_count > 0 {
+		rows = append(rows, receiptRow{Left: "Balance due", Right: receiptMoney(view.Balance_due), Bold: true})
+	}
+
+	rows = append(rows, receiptRow{Rule: true})
+
+	for _, line := range layout.Footer {
+		rows = append(rows, receiptRow{Left: line, Centered: true})
+	}
+
+	return rows
+}
+
+// the invoice's priced lines, or the order's items for invoices created before
+// lines were stored
+func receiptLines(view InvoiceViewFormat) []receiptLine {
+	var lines []receiptLine
+
+	if view.Lines != nil {
+		for _, line := range view.Lines {
+			lines = append(lines, receiptLine{Name: line.Name, Amount: line.Amount, Discount: line.Discount, Parent_order_item_id: line.Parent_order_item_id})
+		}
+
+		return lines
+	}
+
+	items, ok := view.Order_details.(primitive.A)
+
+	if !ok {
+		return lines
+	}
+
+	for _, item := range items {
+		document, ok := item.(bson.M)
+
+		if !ok {
+			continue
+		}
+
+		line := receiptLine{Name: fmt.Sprint(document["food_name"])}
+
+		if amount, ok := document["amount"].(float64); ok {
+			line.Amount = amount
+		}
+
+		if parent, ok := document["parent_order_item_id"].(string); ok {
+			line.Parent_order_item_id = parent
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+func renderReceipt(layout receiptLayout, rows []receiptRow) []byte {
+	lineHeight := func(row receiptRow) float64 {
+		return layout.Font_size * receiptScale(row) * 1.4
+	}
+
+	height := layout.Height
+
+	// a roll is cut to the length of the receipt
+	if height == 0 {
+		height = layout.Margin * 2
+
+		for _, row := range rows {
+			height += lineHeight(row)
+		}
+	}
+
+	document := pdf.New(layout.Width, height)
+	page := document.AddPage()
+	y := layout.Margin
+
+	for _, row := range rows {
+		if y+lineHeight(row) > height-layout.Margin {
+			page = document.AddPage()
+			y = layout.Margin
+		}
+
+		y += lineHeight(row)
+		size := layout.Font_size * receiptScale(row)
+
+		if row.Rule {
+			middle := y - lineHeight(row)/2
+			page.Line(layout.Margin, middle, layout.Width-layout.Margin, middle)
+			continue
+		}
+
+		// the baseline sits a little above the bottom of the row
+		baseline := y - size*0.35
+		available := layout.Width - layout.Margin*2
+
+		if row.Centered {
+			text := fitText(row.Left, available, size)
+			page.Text((layout.Width-pdf.TextWidth(text, size))/2, baseline, size, row.Bold, text)
+			continue
+		}
+
+		right := fitText(row.Right, available, size)
+		left := fitText(row.Left, available-pdf.TextWidth(right, size)-size, size)
+
+		page.Text(layout.Margin, baseline, size, row.Bold, left)
+		page.Text(layout.Width-layout.Margin-pdf.TextWidth(right, size), baseline, size, row.Bold, right)
+	}
+
+	return document.Bytes()
+}
+
+func receiptScale(row receiptRow) float64 {
+	if row.Scale == 0 {
+		return 1
+	}
+
+	return row.Scale
+}
+
+// cuts text that is wider than the available space
+func fitText(text string, available float64, size float64) string {
+	runes := []rune(text)
+
+	for len(runes) > 0 && pdf.TextWidth(string(runes), size) > available {
+		runes = runes[:len(runes)-1]
+	}
+
+	return string(runes)
+}
+
+func splitReceiptText(value string) []string {
+	if value == "" {
+		return nil
+	}
+
+	return strings.Split(value, "|")
+}
+
+func receiptMoney(amount float64) string {
+	return strconv.FormatFloat(toFixed(amount, 2), 'f', 2, 64)
+}
+
+func receiptMethod(method string) string {
+	switch method {
+	case "CASH":
+		return "Cash"
+	case "CARD":
+		return "Card"
+	case "GIFT_CARD":
+		return "Gift card"
+	}
+
+	return method
+}
@@ -0,0 +1,236 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"go-restaurant-management/xlsx"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// columns exported from each list when ?columns= is not given; nested fields
+// are picked with a dot, e.g. columns=invoice_number,lines.name
+var (
+	foodColumns      = []string{"food_id", "name", "price", "menu_id", "availability", "station", "created_at", "updated_at"}
+	menuColumns      = []string{"menu_id", "name", "category", "start_date", "end_date", "created_at", "updated_at"}
+	orderColumns     = []string{"order_id", "order_date", "table_id", "order_type", "server_id", "status", "closed_at", "created_at"}
+	orderItemColumns = []string{"order_item_id", "order_id", "food_id", "combo_id", "parent_order_item_id", "quantity", "unit_price", "seat_number", "voided_at", "void_reason", "created_at"}
+	invoiceColumns   = []string{"invoice_id", "invoice_number", "order_id", "payment_method", "payment_status", "payment_due_date", "subtotal", "discount_total", "tax_total", "service_charge", "total", "tip", "amount_paid", "amount_refunded", "created_at"}
+	tableColumns     = []string{"table_id", "table_number", "number_of_guest", "created_at", "updated_at"}
+	userColumns      = []string{"user_id", "first_name", "last_name", "email", "phone", "user_type", "created_at"}
+)
+
+// a list is exported as CSV with Accept: text/csv or ?format=csv, and as an
+// Excel workbook with ?format=xlsx or the spreadsheet's Accept type
+func exportFormat(c *gin.Context) string {
+	if c.Query("format") == "xlsx" || strings.Contains(c.GetHeader("Accept"), "spreadsheetml") {
+		return "xlsx"
+	}
+
+	if wantsCSV(c) {
+		return "csv"
+	}
+
+	return ""
+}
+
+// streams every document matching filter as a row of the chosen columns, one
+// document at a time; hidden fields are never read from the database
+func exportList(c *gin.Context, format string, collection *mongo.Collection, filter interface{}, name string, columns []string, hidden ...string) {
+	if value := c.Query("columns"); value != "" {
+		columns = []string{}
+
+		for _, column := range strings.Split(value, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				columns = append(columns, column)
+			}
+		}
+	}
+
+	projection := bson.M{}
+
+	for _, field := range hidden {
+		projection[field] = 0
+	}
+
+	for _, column := range columns {
+		if _, ok := projection[strings.Split(column, ".")[0]]; ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "column " + column + " cannot be exported"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	findOptions := options.Find()
+
+	if len(projection) > 0 {
+		findOptions.SetProjection(projection)
+	}
+
+	cursor, err := collection.Find(ctx, filter, findOptions)
+
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while exporting " + name})
+		return
+	}
+
+	defer cursor.Close(ctx)
+
+	header := make([]xlsx.Cell, len(columns))
+
+	for i, column := range columns {
+		header[i] = xlsx.Cell{Value: column}
+	}
+
+	var writeRow func(cells []xlsx.Cell) error
+	var finish func() error
+
+	if format == "xlsx" {
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Header("Content-Disposition", `attachment; filename="`+name+"-"+time.Now().UTC().Format("2006-01-02")+`.xlsx"`)
+		c.Status(http.StatusOK)
+
+		workbook, err := xlsx.NewWriter(c.Writer, name)
+
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		writeRow = workbook.WriteRow
+		finish = workbook.Close
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+name+"-"+time.Now().UTC().Format("2006-01-02")+`.csv"`)
+		c.Status(http.StatusOK)
+
+		writer := csv.NewWriter(c.Writer)
+
+		writeRow = func(cells []xlsx.Cell) error {
+			row := make([]string, len(cells))
+
+			for i, cell := range cells {
+				row[i] = cell.Value
+
+				// spreadsheets would run text starting with these as a formula
+				if !cell.Number && cell.Value != "" && strings.ContainsRune("=+-@", rune(cell.Value[0])) {
+					row[i] = "'" + cell.Value
+				}
+			}
+
+			return writer.Write(row)
+		}
+
+		finish = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	}
+
+	if err := writeRow(header); err != nil {
+		log.Println(err)
+		return
+	}
+
+	// the response has started, so errors from here on can only be logged
+	for cursor.Next(ctx) {
+		var document bson.M
+
+		if err := cursor.Decode(&document); err != nil {
+			log.Println(err)
+			return
+		}
+
+		row := make([]xlsx.Cell, len(columns))
+
+		for i, column := range columns {
+			row[i] = exportCell(documentField(document, column))
+		}
+
+		if err := writeRow(row); err != nil {
+			log.Println(err)
+			return
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		log.Println(err)
+	}
+
+	if err := finish(); err != nil {
+		log.Println(err)
+	}
+}
+
+// follows a dotted path through embedded documents; through an array the rest
+// of the path is followed into every element and the values are joined, so
+// lines.name lists the name of every line
+func documentField(document bson.M, path string) interface{} {
+	return fieldAt(document, strings.Split(path, "."))
+}
+
+func fieldAt(value interface{}, keys []string) interface{} {
+	if len(keys) == 0 {
+		return value
+	}
+
+	switch value := value.(type) {
+	case bson.M:
+		return fieldAt(value[keys[0]], keys[1:])
+	case bson.A:
+		var values []string
+
+		for _, element := range value {
+			if cell := exportCell(fieldAt(element, keys)); cell.Value != "" {
+				values = append(values, cell.Value)
+			}
+		}
+
+		return strings.Join(values, "; ")
+	}
+
+	return nil
+}
+
+func exportCell(value interface{}) xlsx.Cell {
+	switch value := value.(type) {
+	case nil:
+		return xlsx.Cell{}
+	case string:
+		return xlsx.Cell{Value: value}
+	case int32:
+		return xlsx.Cell{Value: strconv.FormatInt(int64(value), 10), Number: true}
+	case int64:
+		return xlsx.Cell{Value: strconv.FormatInt(value, 10), Number: true}
+	case float64:
+		return xlsx.Cell{Value: strconv.FormatFloat(value, 'f', -1, 64), Number: true}
+	case bool:
+		return xlsx.Cell{Value: strconv.FormatBool(value)}
+	case primitive.DateTime:
+		return xlsx.Cell{Value: value.Time().UTC().Format(time.RFC3339)}
+	case primitive.ObjectID:
+		return xlsx.Cell{Value: value.Hex()}
+	}
+
+	// arrays and embedded documents are written as JSON
+	encoded, err := json.Marshal(value)
+
+	if err != nil {
+		return xlsx.Cell{}
+	}
+
+	return xlsx.Cell{Value: string(encoded)}
+}
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDocumentField(t *testing.T) {
+	document := bson.M{
+		"invoice_number": "INV-2026-000001",
+		"total":          12.5,
+		"customer":       bson.M{"name": "Aina"},
+		"lines": bson.A{
+			bson.M{"name": "Nasi lemak", "amount": 8.5, "taxes": bson.A{bson.M{"amount": 0.51}}},
+			bson.M{"name": "Teh tarik", "amount": 4.0, "taxes": bson.A{}},
+			bson.M{"amount": 1.0},
+		},
+		"categories": bson.A{"food", "drinks"},
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"invoice_number", "INV-2026-000001"},
+		{"total", "12.5"},
+		{"customer.name", "Aina"},
+		{"customer.phone", ""},
+		{"lines.name", "Nasi lemak; Teh tarik"},
+		{"lines.amount", "8.5; 4; 1"},
+		{"lines.taxes.amount", "0.51"},
+		{"categories", `["food","drinks"]`},
+		{"total.value", ""},
+		{"missing.name", ""},
+	}
+
+	for _, test := range tests {
+		if got := exportCell(documentField(document, test.path)).Value; got != test.want {
+			t.Errorf("%s = %q, want %q", test.path, got, test.want)
+		}
+	}
+}
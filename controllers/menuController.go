@@ -19,6 +19,11 @@ var menuCollection *mongo.Collection = database.OpenCollection(database.Client,
 
 func GetMenus() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if format := exportFormat(c); format != "" {
+			exportList(c, format, menuCollection, bson.M{}, "menus", menuColumns)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
 		result, err := menuCollection.Find(ctx, bson.M{})
@@ -59,6 +64,48 @@ func GetMenu() gin.HandlerFunc {
 	}
 }
 
+func GetMenuFoods() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		menuId := c.Param("menu_id")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		filter := bson.M{"menu_id": menuId}
+
+		// sold-out foods are hidden unless asked for
+		if c.Query("include_unavailable") != "true" {
+			unavailableFoods, err := unavailableFoodIds(ctx)
+
+			if err != nil {
+				log.Println(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking food availability"})
+				return
+			}
+
+			filter["food_id"] = bson.M{"$nin": unavailableFoods}
+		}
+
+		result, err := foodCollection.Find(ctx, filter)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving foods of the menu"})
+			return
+		}
+
+		var allFoods []bson.M
+
+		if err = result.All(ctx, &allFoods); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allFoods)
+	}
+}
+
 func CreateMenu() gin.HandlerFunc {
 	return func(c *gin.Context) {
 
@@ -0,0 +1,384 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// a food being imported names its menu either by Menu_id or, for menus created
+// by the same import or already in the database, by the menu's name
+type FoodImport struct {
+	models.Food
+	Menu string `json:"menu"`
+}
+
+type MenuImport struct {
+	Menus []models.Menu `json:"menus"`
+	Foods []FoodImport  `json:"foods"`
+
+	parseErrors []ImportError // CSV values that could not be read, e.g. a price that is not a number
+}
+
+type ImportError struct {
+	Sheet string `json:"sheet"` // menus or foods
+	Row   int    `json:"row"`   // counted from 1, not counting a CSV header
+	Error string `json:"error"`
+}
+
+// imports menus and foods from a JSON body, or from the CSV files "menus" and
+// "foods" of a multipart form. Every row is checked first; with ?dry_run=true
+// only the check is done, otherwise the rows are all written in one
+// transaction, or none of them when any row fails.
+func ImportMenus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var menuImport MenuImport
+		var err error
+
+		if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+			menuImport, err = menuImportFromCSV(c)
+		} else {
+			err = c.BindJSON(&menuImport)
+		}
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(menuImport.Menus) == 0 && len(menuImport.Foods) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to import"})
+			return
+		}
+
+		dryRun := c.Query("dry_run") == "true"
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		menus, foods, importErrors, err := prepareMenuImport(ctx, menuImport)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the import"})
+			return
+		}
+
+		if dryRun || len(importErrors) > 0 {
+			status := http.StatusOK
+
+			if !dryRun {
+				status = http.StatusBadRequest
+			}
+
+			c.JSON(status, gin.H{
+				"dry_run": dryRun,
+				"menus":   len(menus),
+				"foods":   len(foods),
+				"errors":  importErrors,
+			})
+			return
+		}
+
+		err = database.Transaction(ctx, func(ctx context.Context) error {
+			if len(menus) > 0 {
+				if _, err := menuCollection.InsertMany(ctx, menus); err != nil {
+					return err
+				}
+			}
+
+			if len(foods) > 0 {
+				if _, err := foodCollection.InsertMany(ctx, foods); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "import failed, nothing was imported"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run": false,
+			"menus":   len(menus),
+			"foods":   len(foods),
+			"errors":  importErrors,
+		})
+	}
+}
+
+// validates every row the way CreateMenu and CreateFood do and resolves menu
+// names, returning the documents to insert and the rows that failed
+func prepareMenuImport(ctx context.Context, menuImport MenuImport) ([]interface{}, []interface{}, []ImportError, error) {
+	importErrors := []ImportError{}
+	menus := []interface{}{}
+	foods := []interface{}{}
+
+	// rows with unreadable values are reported as they are and not checked further
+	unreadable := map[string]bool{}
+
+	for _, parseError := range menuImport.parseErrors {
+		importErrors = append(importErrors, parseError)
+		unreadable[parseError.Sheet+strconv.Itoa(parseError.Row)] = true
+	}
+
+	now, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// menu ids by name; a name used by more than one menu cannot be resolved
+	menuIds := map[string][]string{}
+
+	result, err := menuCollection.Find(ctx, bson.M{})
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var existingMenus []models.Menu
+
+	if err := result.All(ctx, &existingMenus); err != nil {
+		return nil, nil, nil, err
+	}
+
+	knownMenus := map[string]bool{}
+
+	for _, menu := range existingMenus {
+		menuIds[menu.Name] = append(menuIds[menu.Name], menu.Menu_id)
+		knownMenus[menu.Menu_id] = true
+	}
+
+	for i, menu := range menuImport.Menus {
+		if unreadable["menus"+strconv.Itoa(i+1)] {
+			continue
+		}
+
+		if err := validate.Struct(menu); err != nil {
+			importErrors = append(importErrors, ImportError{Sheet: "menus", Row: i + 1, Error: err.Error()})
+			continue
+		}
+
+		if menu.Start_date != nil && menu.End_date != nil && menu.End_date.Before(*menu.Start_date) {
+			importErrors = append(importErrors, ImportError{Sheet: "menus", Row: i + 1, Error: "end_date must not be before start_date"})
+			continue
+		}
+
+		menu.Created_at = now
+		menu.Updated_at = now
+		menu.ID = primitive.NewObjectID()
+		menu.Menu_id = menu.ID.Hex()
+
+		menuIds[menu.Name] = append(menuIds[menu.Name], menu.Menu_id)
+		knownMenus[menu.Menu_id] = true
+		menus = append(menus, menu)
+	}
+
+	for i, foodImport := range menuImport.Foods {
+		if unreadable["foods"+strconv.Itoa(i+1)] {
+			continue
+		}
+
+		food := foodImport.Food
+
+		if food.Menu_id == nil && foodImport.Menu != "" {
+			ids := menuIds[foodImport.Menu]
+
+			switch len(ids) {
+			case 0:
+				importErrors = append(importErrors, ImportError{Sheet: "foods", Row: i + 1, Error: "menu " + foodImport.Menu + " not found"})
+				continue
+			case 1:
+				food.Menu_id = &ids[0]
+			default:
+				importErrors = append(importErrors, ImportError{Sheet: "foods", Row: i + 1, Error: "more than one menu is named " + foodImport.Menu + ", use menu_id"})
+				continue
+			}
+		}
+
+		if err := validate.Struct(food); err != nil {
+			importErrors = append(importErrors, ImportError{Sheet: "foods", Row: i + 1, Error: err.Error()})
+			continue
+		}
+
+		if !knownMenus[*food.Menu_id] {
+			importErrors = append(importErrors, ImportError{Sheet: "foods", Row: i + 1, Error: "menu not found"})
+			continue
+		}
+
+		food.Created_at = now
+		food.Updated_at = now
+		food.ID = primitive.NewObjectID()
+		food.Food_id = food.ID.Hex()
+		price := toFixed(*food.Price, 2)
+		food.Price = &price
+
+		foods = append(foods, food)
+	}
+
+	return menus, foods, importErrors, nil
+}
+
+// reads the "menus" and "foods" files of the form, each a CSV file whose
+// header names the columns after the JSON fields
+func menuImportFromCSV(c *gin.Context) (MenuImport, error) {
+	var menuImport MenuImport
+
+	menuRows, err := formCSV(c, "menus")
+
+	if err != nil {
+		return menuImport, err
+	}
+
+	foodRows, err := formCSV(c, "foods")
+
+	if err != nil {
+		return menuImport, err
+	}
+
+	for i, row := range menuRows {
+		menu := models.Menu{Name: row["name"], Category: row["category"]}
+
+		if menu.Start_date, err = importDate(row["start_date"]); err != nil {
+			menuImport.parseErrors = append(menuImport.parseErrors, ImportError{Sheet: "menus", Row: i + 1, Error: "start_date must be formatted as YYYY-MM-DD"})
+		}
+
+		if menu.End_date, err = importDate(row["end_date"]); err != nil {
+			menuImport.parseErrors = append(menuImport.parseErrors, ImportError{Sheet: "menus", Row: i + 1, Error: "end_date must be formatted as YYYY-MM-DD"})
+		}
+
+		menuImport.Menus = append(menuImport.Menus, menu)
+	}
+
+	for i, row := range foodRows {
+		foodImport := FoodImport{Menu: row["menu"]}
+		foodImport.Name = importString(row["name"])
+		foodImport.Food_image = importString(row["food_image"])
+		foodImport.Menu_id = importString(row["menu_id"])
+		foodImport.Availability = importString(row["availability"])
+		foodImport.Station = importString(row["station"])
+
+		if row["price"] != "" {
+			price, err := strconv.ParseFloat(row["price"], 64)
+
+			if err != nil {
+				menuImport.parseErrors = append(menuImport.parseErrors, ImportError{Sheet: "foods", Row: i + 1, Error: "price must be a number"})
+			}
+
+			foodImport.Price = &price
+		}
+
+		menuImport.Foods = append(menuImport.Foods, foodImport)
+	}
+
+	return menuImport, nil
+}
+
+// rows of the named CSV file of the form as maps of column to value; a missing
+// file has no rows
+func formCSV(c *gin.Context, name string) ([]map[string]string, error) {
+	header, err := c.FormFile(name)
+
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := header.Open()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	columns, err := reader.Read()
+
+	if err == io.EOF {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, errors.New(name + ": " + err.Error())
+	}
+
+	for i, column := range columns {
+		columns[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+	}
+
+	rows := []map[string]string{}
+
+	for {
+		record, err := reader.Read()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.New(name + ": " + err.Error())
+		}
+
+		row := map[string]string{}
+
+		for i, value := range record {
+			if i < len(columns) {
+				row[columns[i]] = strings.TrimSpace(value)
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func importString(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
+
+// dates are given as YYYY-MM-DD or RFC 3339
+func importDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+
+	if err != nil {
+		date, err = time.Parse(time.RFC3339, value)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &date, nil
+}
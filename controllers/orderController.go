@@ -19,6 +19,11 @@ var orderCollection *mongo.Collection = database.OpenCollection(database.Client,
 
 func GetOrders() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if format := exportFormat(c); format != "" {
+			exportList(c, format, orderCollection, bson.M{}, "orders", orderColumns)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
 		result, err := orderCollection.Find(ctx, bson.M{})
@@ -106,6 +111,8 @@ func CreateOrder() gin.HandlerFunc {
 
 		order.ID = primitive.NewObjectID()
 		order.Order_id = order.ID.Hex()
+		order.Server_id = c.GetString("uid")
+		order.Status = "OPEN"
 
 		result, insertErr := orderCollection.InsertOne(ctx, order)
 		defer cancel()
@@ -146,6 +153,16 @@ func UpdateOrder() gin.HandlerFunc {
 			updateObj = append(updateObj, bson.E{Key: "table_id", Value: order.Table_id})
 		}
 
+		if order.Order_type != nil {
+			if err := validate.Var(order.Order_type, "eq=DINE_IN|eq=TAKEAWAY|eq=DELIVERY"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				defer cancel()
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "order_type", Value: order.Order_type})
+		}
+
 		var err error
 		order.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
@@ -183,36 +200,20 @@ func UpdateOrder() gin.HandlerFunc {
 	}
 }
 
-func OrderItemOrderCreator(order models.Order) (string, error) {
-
+// fills in the ids, timestamps and status of an order taken with its items
+func prepareOrder(order models.Order) (models.Order, error) {
 	var err error
 
 	order.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 
 	if err != nil {
-		log.Println(err)
-		return "", err
-	}
-
-	order.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-
-	if err != nil {
-		log.Println(err)
-		return "", err
+		return order, err
 	}
 
+	order.Updated_at = order.Created_at
 	order.ID = primitive.NewObjectID()
 	order.Order_id = order.ID.Hex()
+	order.Status = "OPEN"
 
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-
-	_, insertErr := orderCollection.InsertOne(ctx, order)
-	defer cancel()
-
-	if insertErr != nil {
-		return "", insertErr
-	}
-
-	return order.Order_id, nil
-
+	return order, nil
 }
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"go-restaurant-management/database"
 	"go-restaurant-management/models"
 	"log"
@@ -12,18 +13,30 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+type VoidRequest struct {
+	Reason       *string                 `json:"reason" validate:"required,min=3"`
+	Approval     *models.ManagerApproval `json:"approval" validate:"required"`
+	Waste_reason *string                 `json:"waste_reason" validate:"omitempty,eq=DROPPED|eq=EXPIRED|eq=RETURNED|eq=SPOILED|eq=OTHER"`
+}
+
 type OrderItemPack struct {
 	Table_id    *string
+	Order_type  *string `validate:"omitempty,eq=DINE_IN|eq=TAKEAWAY|eq=DELIVERY"`
 	Order_items []models.OrderItem
+	Combos      []ComboSelection
 }
 
 var orderItemCollection *mongo.Collection = database.OpenCollection(database.Client, "orderItem")
 
 func GetOrderItems() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if format := exportFormat(c); format != "" {
+			exportList(c, format, orderItemCollection, bson.M{}, "orderItems", orderItemColumns)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
 		result, err := orderItemCollection.Find(ctx, bson.M{})
@@ -88,6 +101,8 @@ func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
 	matchStage := bson.D{
 		{Key: "$match", Value: bson.D{
 			{Key: "order_id", Value: id},
+			// voided items are not charged
+			{Key: "voided_at", Value: nil},
 		}},
 	}
 
@@ -121,6 +136,40 @@ func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
 		}},
 	}
 
+	lookupComboStage := bson.D{
+		{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "combo"},
+			{Key: "localField", Value: "combo_id"},
+			{Key: "foreignField", Value: "combo_id"},
+			{Key: "as", Value: "combo"},
+		}},
+	}
+
+	unwindComboStage := bson.D{
+		{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$combo"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}},
+	}
+
+	// a combo line is charged at the bundle price and its child items are free,
+	// so the invoice shows the bundle price instead of the sum of the components
+	amount := bson.D{
+		{Key: "$switch", Value: bson.D{
+			{Key: "branches", Value: bson.A{
+				bson.D{
+					{Key: "case", Value: bson.D{{Key: "$gt", Value: bson.A{"$combo_id", nil}}}},
+					{Key: "then", Value: "$combo.price"},
+				},
+				bson.D{
+					{Key: "case", Value: bson.D{{Key: "$gt", Value: bson.A{"$parent_order_item_id", nil}}}},
+					{Key: "then", Value: 0},
+				},
+			}},
+			{Key: "default", Value: "$food.price"},
+		}},
+	}
+
 	lookupOrderStage := bson.D{
 		{Key: "$lookup", Value: bson.D{
 			{Key: "from", Value: "order"},
@@ -159,15 +208,18 @@ func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
 	projectStage := bson.D{
 		{Key: "$project", Value: bson.D{
 			{Key: "id", Value: 0},
-			{Key: "amount", Value: "$food.price"},
+			{Key: "amount", Value: amount},
 			{Key: "total_count", Value: 1},
-			{Key: "food_name", Value: "$food.name"},
+			{Key: "food_name", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$food.name", "$combo.name"}}}},
 			{Key: "food_image", Value: "$food.food_image"},
 			{Key: "table_number", Value: "$table.table_number"},
 			{Key: "table_id", Value: "$table.table_id"},
 			{Key: "order_id", Value: "$order.order_id"},
-			{Key: "price", Value: "$food.price"},
+			{Key: "price", Value: amount},
 			{Key: "quantity", Value: 1},
+			{Key: "order_item_id", Value: 1},
+			{Key: "combo_id", Value: 1},
+			{Key: "parent_order_item_id", Value: 1},
 		}},
 	}
 
@@ -200,6 +252,8 @@ func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
 		matchStage,
 		lookupFoodStage,
 		unwindFoodStage,
+		lookupComboStage,
+		unwindComboStage,
 		lookupOrderStage,
 		unwindOrderStage,
 		lookupTableStage,
@@ -245,9 +299,46 @@ func CreateOrderItem() gin.HandlerFunc {
 		}
 
 		order.Table_id = orderItemPack.Table_id
+		order.Order_type = orderItemPack.Order_type
+		order.Server_id = c.GetString("uid")
+
+		if validationError := validate.Struct(orderItemPack); validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
 
-		// create an order whenever need to create an order item
-		order_id, err := OrderItemOrderCreator(order)
+		orderedFoods := []orderedFood{}
+
+		for _, orderItem := range orderItemPack.Order_items {
+			if orderItem.Food_id != nil {
+				orderedFoods = append(orderedFoods, orderedFood{foodId: *orderItem.Food_id, size: orderItem.Quantity})
+			}
+		}
+
+		for _, selection := range orderItemPack.Combos {
+			for _, foodId := range selection.Choices {
+				orderedFoods = append(orderedFoods, orderedFood{foodId: foodId, size: selection.Quantity})
+			}
+		}
+
+		availabilityCtx, availabilityCancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err = checkFoodsAvailable(availabilityCtx, orderedFoods)
+		defer availabilityCancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(orderItemPack.Order_items) == 0 && len(orderItemPack.Combos) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "an order needs at least one item"})
+			return
+		}
+
+		// the order is only written together with its items, once every item is valid
+		order, err = prepareOrder(order)
 
 		if err != nil {
 			log.Println(err)
@@ -255,7 +346,10 @@ func CreateOrderItem() gin.HandlerFunc {
 			return
 		}
 
+		order_id := order.Order_id
+
 		orderItemsToBeInserted := []interface{}{}
+		orderItems := []models.OrderItem{}
 
 		// inserting order items for each order
 		for _, orderItem := range orderItemPack.Order_items {
@@ -293,19 +387,79 @@ func CreateOrderItem() gin.HandlerFunc {
 
 			// insert the order item into an array
 			orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
+			orderItems = append(orderItems, orderItem)
 		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
-		insertOrderItemsResult, insertError := orderItemCollection.InsertMany(ctx, orderItemsToBeInserted)
+		// each combo is ordered as one line and expanded into child items for the kitchen
+		for _, selection := range orderItemPack.Combos {
+			validationError := validate.Struct(selection)
+
+			if validationError != nil {
+				log.Println(validationError)
+				c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+				defer cancel()
+				return
+			}
+
+			comboItems, err := expandComboSelection(ctx, selection, order_id)
+
+			if err != nil {
+				log.Println(err)
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				defer cancel()
+				return
+			}
+
+			for _, orderItem := range comboItems {
+				orderItem.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+				if err != nil {
+					log.Println(err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+					defer cancel()
+					return
+				}
+
+				orderItem.Updated_at = orderItem.Created_at
+
+				orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
+				orderItems = append(orderItems, orderItem)
+			}
+		}
+
+		var insertOrderItemsResult *mongo.InsertManyResult
+
+		// the order, its items and the stock they use are written all together or not at all
+		insertError := database.Transaction(ctx, func(ctx context.Context) error {
+			if _, err := orderCollection.InsertOne(ctx, order); err != nil {
+				return err
+			}
+
+			result, err := orderItemCollection.InsertMany(ctx, orderItemsToBeInserted)
+
+			if err != nil {
+				return err
+			}
+
+			insertOrderItemsResult = result
+
+			return adjustStockForOrderItems(ctx, orderItems, -1)
+		})
 		defer cancel()
 
 		if insertError != nil {
 			log.Println(insertError)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": insertError.Error()})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order was not created due to some errors"})
 			return
 		}
 
+		// the order is taken even when a kitchen printer cannot be reached
+		if err := printKitchenTickets(ctx, order, order_id, orderItems); err != nil {
+			log.Println(err)
+		}
+
 		c.JSON(http.StatusOK, insertOrderItemsResult)
 	}
 }
@@ -327,12 +481,15 @@ func UpdateOrderItem() gin.HandlerFunc {
 			updateObj = append(updateObj, bson.E{Key: "unit_price", Value: orderItem.Unit_price})
 		}
 
-		if orderItem.Quantity != nil {
-			updateObj = append(updateObj, bson.E{Key: "quantity", Value: orderItem.Quantity})
+		// the stock, availability and combo slots were settled for the food and
+		// size when the item was ordered
+		if orderItem.Quantity != nil || orderItem.Food_id != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "the food and size of an order item cannot be changed, void it and order again"})
+			return
 		}
 
-		if orderItem.Food_id != nil {
-			updateObj = append(updateObj, bson.E{Key: "food_id", Value: orderItem.Food_id})
+		if orderItem.Seat_number != nil {
+			updateObj = append(updateObj, bson.E{Key: "seat_number", Value: orderItem.Seat_number})
 		}
 
 		var err error
@@ -346,11 +503,7 @@ func UpdateOrderItem() gin.HandlerFunc {
 
 		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: orderItem.Updated_at})
 
-		upsert := true
 		filter := bson.M{"order_item_id": orderItemId}
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
@@ -360,7 +513,6 @@ func UpdateOrderItem() gin.HandlerFunc {
 			bson.D{
 				{Key: "$set", Value: updateObj},
 			},
-			&opt,
 		)
 		defer cancel()
 
@@ -370,6 +522,212 @@ func UpdateOrderItem() gin.HandlerFunc {
 			return
 		}
 
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "order item not found"})
+			return
+		}
+
 		c.JSON(http.StatusOK, result)
 	}
 }
+
+var errOrderItemVoided = errors.New("order item is already voided")
+
+func VoidOrderItem() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		orderItemId := c.Param("orderItem_id")
+		var orderItem models.OrderItem
+		var voidRequest VoidRequest
+
+		// a waste_reason flags the voided dish as waste
+		if err := c.BindJSON(&voidRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(voidRequest)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": orderItemId}).Decode(&orderItem)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order item not found"})
+			return
+		}
+
+		if orderItem.Voided_at != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errOrderItemVoided.Error()})
+			return
+		}
+
+		managerId, err := approvingManager(ctx, voidRequest.Approval)
+
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		// once the guest has paid, the money goes back through a refund instead
+		paidCount, err := invoiceCollection.CountDocuments(ctx, bson.M{
+			"order_id":       orderItem.Order_id,
+			"payment_status": bson.M{"$nin": bson.A{"PENDING", "PARTIALLY_PAID"}},
+		})
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving invoices from database"})
+			return
+		}
+
+		if paidCount > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvoicePaid.Error()})
+			return
+		}
+
+		// voiding a combo line voids its child items as well
+		filter := bson.M{
+			"$or": bson.A{
+				bson.M{"order_item_id": orderItemId},
+				bson.M{"parent_order_item_id": orderItemId},
+			},
+			"voided_at": nil,
+		}
+
+		voided_at, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing voided_at"})
+			return
+		}
+
+		var updateResult *mongo.UpdateResult
+
+		// the items, the invoices' history and the stock or waste change together
+		err = database.Transaction(ctx, func(ctx context.Context) error {
+			// the items are read inside the transaction, so a void that got in
+			// first leaves nothing to void on a retry
+			result, err := orderItemCollection.Find(ctx, filter)
+
+			if err != nil {
+				return err
+			}
+
+			var voidedItems []models.OrderItem
+
+			if err := result.All(ctx, &voidedItems); err != nil {
+				return err
+			}
+
+			if len(voidedItems) == 0 {
+				return errOrderItemVoided
+			}
+
+			var voidedIds []string
+			voidedAmount := 0.0
+
+			for _, voidedItem := range voidedItems {
+				voidedIds = append(voidedIds, voidedItem.Order_item_id)
+
+				if voidedItem.Unit_price != nil {
+					voidedAmount += *voidedItem.Unit_price
+				}
+			}
+
+			updated, err := orderItemCollection.UpdateMany(
+				ctx,
+				bson.M{"order_item_id": bson.M{"$in": voidedIds}, "voided_at": nil},
+				bson.D{
+					{Key: "$set", Value: bson.D{
+						{Key: "voided_at", Value: voided_at},
+						{Key: "void_reason", Value: voidRequest.Reason},
+						{Key: "voided_by", Value: c.GetString("uid")},
+						{Key: "void_approved_by", Value: managerId},
+						{Key: "updated_at", Value: voided_at},
+					}},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			if updated.ModifiedCount != int64(len(voidedItems)) {
+				return errOrderItemVoided
+			}
+
+			updateResult = updated
+
+			voided := map[string]bool{}
+
+			for _, voidedItem := range voidedItems {
+				voided[voidedItem.Order_item_id] = true
+			}
+
+			// the order's invoices no longer charge for the items and keep the void in their history
+			err = repriceVoidedInvoices(ctx, orderItem.Order_id, voided, models.InvoiceEvent{
+				Type:         "VOID",
+				Reference_id: orderItemId,
+				Amount:       toFixed(voidedAmount, 2),
+				Reason:       *voidRequest.Reason,
+				User_id:      c.GetString("uid"),
+				Approved_by:  managerId,
+				Created_at:   voided_at,
+			})
+
+			if err != nil {
+				return err
+			}
+
+			if voidRequest.Waste_reason == nil {
+				// the dish was never made, so its ingredients go back into stock
+				return adjustStockForOrderItems(ctx, voidedItems, 1)
+			}
+
+			// the dish was made and thrown away, its stock stays deducted
+			for _, voidedItem := range voidedItems {
+				if voidedItem.Food_id == nil {
+					continue
+				}
+
+				quantity := 1.0
+				itemId := voidedItem.Order_item_id
+				waste := models.Waste{
+					Reason:        voidRequest.Waste_reason,
+					Food_id:       voidedItem.Food_id,
+					Size:          voidedItem.Quantity,
+					Quantity:      &quantity,
+					Order_item_id: &itemId,
+					User_id:       c.GetString("uid"),
+				}
+
+				if _, err := recordWaste(ctx, &waste, false); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+		if err == errInvoicePaid || err == errOrderItemVoided {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "order item void failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, updateResult)
+	}
+}
@@ -0,0 +1,537 @@
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"go-restaurant-management/database"
+	helper "go-restaurant-management/helpers"
+	"go-restaurant-management/models"
+	"go-restaurant-management/payments"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var paymentCollection *mongo.Collection = database.OpenCollection(database.Client, "payment")
+
+func GetInvoicePayments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invoiceId := c.Param("invoice_id")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := paymentCollection.Find(ctx, bson.M{"invoice_id": invoiceId})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving payments from database"})
+			return
+		}
+
+		var allPayments []bson.M
+
+		if err := result.All(ctx, &allPayments); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allPayments)
+	}
+}
+
+func CreatePayment() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invoiceId := c.Param("invoice_id")
+		var payment models.Payment
+
+		if err := c.BindJSON(&payment); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(payment)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		payment.Invoice_id = invoiceId
+		payment.User_id = c.GetString("uid")
+		payment.Idempotency_key = c.GetHeader("Idempotency-Key")
+
+		invoice, err := recordPayment(ctx, &payment)
+
+		if errors.Is(err, payments.ErrDeclined) {
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+			return
+		}
+
+		if errors.Is(err, payments.ErrTimeout) {
+			log.Println(err)
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"payment":        payment,
+			"amount_paid":    invoice.Amount_paid,
+			"balance_due":    fromCents(toCents(invoice.Total) - toCents(invoice.Amount_paid)),
+			"payment_status": invoice.Payment_status,
+		})
+	}
+}
+
+// applies the payment to its invoice and stores it in the ledger; the invoice is
+// PARTIALLY_PAID until the payments cover its total, then PAID. Card payments go
+// through the payment provider and stay PENDING when it confirms them later.
+func recordPayment(ctx context.Context, payment *models.Payment) (*models.Invoice, error) {
+	var invoice models.Invoice
+
+	if err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": payment.Invoice_id}).Decode(&invoice); err != nil {
+		return nil, errors.New("invoice not found")
+	}
+
+	if err := legacyInvoiceTotal(&invoice); err != nil {
+		return nil, err
+	}
+
+	// a retried request gets the payment recorded the first time
+	if payment.Idempotency_key != "" {
+		var existing models.Payment
+
+		err := paymentCollection.FindOne(ctx, bson.M{"invoice_id": payment.Invoice_id, "idempotency_key": payment.Idempotency_key}).Decode(&existing)
+
+		if err == nil {
+			*payment = existing
+			return &invoice, nil
+		}
+
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+	}
+
+	if invoice.Payment_status != nil && *invoice.Payment_status == "PAID" {
+		return nil, errors.New("invoice is already paid")
+	}
+
+	amount := toCents(*payment.Amount)
+
+	if amount > toCents(invoice.Total)-toCents(invoice.Amount_paid) {
+		return nil, errors.New("payment exceeds the balance due")
+	}
+
+	var tip int64
+
+	if payment.Tip != nil {
+		tip = toCents(*payment.Tip)
+	}
+
+	payment.Amount = floatPointer(fromCents(amount))
+	payment.Tip = floatPointer(fromCents(tip))
+	payment.Change_given = 0
+
+	if payment.Tendered != nil {
+		if *payment.Method != "CASH" {
+			return nil, errors.New("only cash payments can be tendered")
+		}
+
+		change := toCents(*payment.Tendered) - amount - tip
+
+		if change < 0 {
+			return nil, errors.New("tendered cash does not cover the payment")
+		}
+
+		payment.Change_given = fromCents(change)
+	}
+
+	now, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return nil, err
+	}
+
+	payment.Created_at = now
+	payment.ID = primitive.NewObjectID()
+	payment.Payment_id = payment.ID.Hex()
+	payment.Status = "COMPLETED"
+
+	if payment.Idempotency_key == "" {
+		payment.Idempotency_key = payment.Payment_id
+	}
+
+	// cash goes into the drawer of the till the user opened
+	if *payment.Method == "CASH" {
+		session, err := openTillSession(ctx, payment.User_id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		payment.Till_session_id = session.Till_session_id
+	}
+
+	if *payment.Method == "CARD" {
+		if err := chargeCard(ctx, payment); err != nil {
+			return nil, err
+		}
+
+		// the webhook applies the payment once the provider confirms it
+		if payment.Status == payments.StatusPending {
+			if _, err := paymentCollection.InsertOne(ctx, payment); err != nil {
+				return nil, err
+			}
+
+			return &invoice, nil
+		}
+	}
+
+	// the payment is only in the ledger when it was applied to the invoice; a
+	// retried transaction starts again from the invoice as it was read
+	var applied models.Invoice
+
+	err = database.Transaction(ctx, func(ctx context.Context) error {
+		applied = invoice
+
+		if err := applyPayment(ctx, &applied, payment); err != nil {
+			return err
+		}
+
+		_, err := paymentCollection.InsertOne(ctx, payment)
+
+		return err
+	})
+
+	if err != nil {
+		if *payment.Method == "CARD" {
+			refundCard(ctx, payment)
+		}
+
+		return nil, err
+	}
+
+	return &applied, nil
+}
+
+// adds the payment to the invoice's amount paid and tip, and closes the order
+// once the invoice is paid
+func applyPayment(ctx context.Context, invoice *models.Invoice, payment *models.Payment) error {
+	if err := legacyInvoiceTotal(invoice); err != nil {
+		return err
+	}
+
+	amount := toCents(*payment.Amount)
+	due := toCents(invoice.Total) - toCents(invoice.Amount_paid)
+
+	if amount > due {
+		return errors.New("payment exceeds the balance due")
+	}
+
+	// a second tender with another method makes the invoice MIXED
+	method := *payment.Method
+
+	if invoice.Amount_paid > 0 && invoice.Payment_method != nil && *invoice.Payment_method != method {
+		method = "MIXED"
+	}
+
+	status := "PARTIALLY_PAID"
+
+	if amount == due {
+		status = "PAID"
+	}
+
+	now, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return err
+	}
+
+	previousTip := 0.0
+
+	if invoice.Tip != nil {
+		previousTip = *invoice.Tip
+	}
+
+	amountPaid := fromCents(toCents(invoice.Amount_paid) + amount)
+	invoiceTip := fromCents(toCents(previousTip) + toCents(*payment.Tip))
+
+	// the filter on amount_paid keeps two tills from paying the same balance twice
+	filter := bson.M{"invoice_id": invoice.Invoice_id, "amount_paid": invoice.Amount_paid}
+
+	if invoice.Amount_paid == 0 {
+		filter["amount_paid"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	// the invoice and its order's status change together
+	err = database.Transaction(ctx, func(ctx context.Context) error {
+		result, err := invoiceCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "amount_paid", Value: amountPaid},
+					{Key: "total", Value: invoice.Total},
+					{Key: "tip", Value: invoiceTip},
+					{Key: "payment_method", Value: method},
+					{Key: "payment_status", Value: status},
+					{Key: "updated_at", Value: now},
+				}},
+				{Key: "$push", Value: bson.D{
+					{Key: "history", Value: models.InvoiceEvent{
+						Type:         "PAYMENT",
+						Reference_id: payment.Payment_id,
+						Amount:       fromCents(amount),
+						User_id:      payment.User_id,
+						Created_at:   now,
+					}},
+				}},
+			},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if result.MatchedCount == 0 {
+			return errors.New("invoice was changed by another payment, please retry")
+		}
+
+		if status == "PAID" {
+			return closeOrderIfPaid(ctx, invoice.Invoice_id)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	invoice.Amount_paid = amountPaid
+	invoice.Tip = &invoiceTip
+	invoice.Payment_method = &method
+	invoice.Payment_status = &status
+
+	return nil
+}
+
+// authorizes and captures the amount and tip on the card; the idempotency key
+// makes a retried request reuse the provider's earlier answer
+func chargeCard(ctx context.Context, payment *models.Payment) error {
+	provider, err := payments.Provider()
+
+	if err != nil {
+		return err
+	}
+
+	total := toCents(*payment.Amount) + toCents(*payment.Tip)
+
+	authorization, err := provider.Authorize(ctx, payments.AuthorizeRequest{
+		Card_token:      *payment.Card_token,
+		Amount:          total,
+		Currency:        helper.GetEnvVariable("CURRENCY"),
+		Idempotency_key: payment.Idempotency_key,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	payment.Provider = provider.Name()
+	payment.Provider_reference = authorization.Reference
+
+	if authorization.Status == payments.StatusPending {
+		payment.Status = payments.StatusPending
+		return nil
+	}
+
+	if _, err := provider.Capture(ctx, authorization.Reference, total, payment.Idempotency_key); err != nil {
+		// release the hold so the guest is not charged for a failed payment
+		if _, voidErr := provider.Void(ctx, authorization.Reference, payment.Idempotency_key); voidErr != nil {
+			log.Println(voidErr)
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// gives back a captured card payment that could not be applied to its invoice
+func refundCard(ctx context.Context, payment *models.Payment) {
+	provider, err := payments.Provider()
+
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	total := toCents(*payment.Amount) + toCents(*payment.Tip)
+
+	if _, err := provider.Refund(ctx, payment.Provider_reference, total, payment.Idempotency_key); err != nil {
+		log.Println(err)
+	}
+}
+
+// lets a provider that settles pending payments on their webhook, like the
+// mock provider, capture the payment so it can be refunded later
+func confirmCard(payment *models.Payment) {
+	provider, err := payments.Provider()
+
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	confirmer, ok := provider.(payments.Confirmer)
+
+	if !ok {
+		return
+	}
+
+	if err := confirmer.Confirm(payment.Provider_reference); err != nil {
+		log.Println(err)
+	}
+}
+
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+type PaymentWebhook struct {
+	Provider_reference *string `json:"provider_reference" validate:"required"`
+	Status             *string `json:"status" validate:"required,eq=CAPTURED|eq=DECLINED|eq=VOIDED"`
+}
+
+// called by asynchronous providers to confirm or decline a PENDING card payment;
+// the body is signed with the shared PAYMENT_WEBHOOK_SECRET in X-Signature
+func PaymentWebhookHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := c.GetRawData()
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !validWebhookSignature(body, c.GetHeader("X-Signature")) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+			return
+		}
+
+		var webhook PaymentWebhook
+
+		if err := json.Unmarshal(body, &webhook); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(webhook)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		status := "DECLINED"
+
+		if *webhook.Status == payments.StatusCaptured {
+			status = "COMPLETED"
+		}
+
+		// claiming the pending payment makes a repeated webhook a no-op
+		var payment models.Payment
+
+		err = paymentCollection.FindOneAndUpdate(
+			ctx,
+			bson.M{"provider_reference": *webhook.Provider_reference, "status": payments.StatusPending},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: status}}}},
+		).Decode(&payment)
+
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusOK, gin.H{"status": "already processed"})
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while confirming the payment"})
+			return
+		}
+
+		if status == "DECLINED" {
+			c.JSON(http.StatusOK, gin.H{"status": status})
+			return
+		}
+
+		confirmCard(&payment)
+
+		var invoice models.Invoice
+
+		err = invoiceCollection.FindOne(ctx, bson.M{"invoice_id": payment.Invoice_id}).Decode(&invoice)
+
+		if err == nil {
+			err = applyPayment(ctx, &invoice, &payment)
+		}
+
+		// the balance was settled some other way in the meantime
+		if err != nil {
+			log.Println(err)
+			refundCard(ctx, &payment)
+
+			_, updateErr := paymentCollection.UpdateOne(
+				ctx,
+				bson.M{"payment_id": payment.Payment_id},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: payments.StatusRefunded}}}},
+			)
+
+			if updateErr != nil {
+				log.Println(updateErr)
+			}
+
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": status, "payment_status": invoice.Payment_status})
+	}
+}
+
+func validWebhookSignature(body []byte, signature string) bool {
+	secret := helper.GetEnvVariable("PAYMENT_WEBHOOK_SECRET")
+
+	if secret == "" || signature == "" {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func sign(secret string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidWebhookSignature(t *testing.T) {
+	t.Setenv("PAYMENT_WEBHOOK_SECRET", "whsec")
+	body := []byte(`{"provider_reference":"mock_1","status":"CAPTURED"}`)
+
+	if !validWebhookSignature(body, sign("whsec", string(body))) {
+		t.Error("a body signed with the secret should be valid")
+	}
+
+	if validWebhookSignature(body, sign("other", string(body))) {
+		t.Error("a body signed with another secret should be invalid")
+	}
+
+	if validWebhookSignature([]byte(`{"provider_reference":"mock_2","status":"CAPTURED"}`), sign("whsec", string(body))) {
+		t.Error("a changed body should be invalid")
+	}
+
+	if validWebhookSignature(body, "") {
+		t.Error("an unsigned body should be invalid")
+	}
+
+	t.Setenv("PAYMENT_WEBHOOK_SECRET", "")
+
+	if validWebhookSignature(body, sign("", string(body))) {
+		t.Error("without a secret no webhook should be accepted")
+	}
+}
+
+// the cases stop before the handler reaches the database
+func TestPaymentWebhookHandlerSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("PAYMENT_WEBHOOK_SECRET", "whsec")
+
+	router := gin.New()
+	router.POST("/webhooks/payments", PaymentWebhookHandler())
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		want      int
+	}{
+		{"unsigned", `{"provider_reference":"mock_1","status":"CAPTURED"}`, "", http.StatusUnauthorized},
+		{"wrong secret", `{"provider_reference":"mock_1","status":"CAPTURED"}`, sign("other", `{"provider_reference":"mock_1","status":"CAPTURED"}`), http.StatusUnauthorized},
+		{"tampered body", `{"provider_reference":"mock_1","status":"CAPTURED"}`, sign("whsec", `{"provider_reference":"mock_1","status":"DECLINED"}`), http.StatusUnauthorized},
+		{"signed but malformed", `{"provider_reference":`, sign("whsec", `{"provider_reference":`), http.StatusBadRequest},
+		{"signed but unknown status", `{"provider_reference":"mock_1","status":"PAID"}`, sign("whsec", `{"provider_reference":"mock_1","status":"PAID"}`), http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/webhooks/payments", strings.NewReader(test.body))
+
+			if test.signature != "" {
+				request.Header.Set("X-Signature", test.signature)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != test.want {
+				t.Errorf("status %d, want %d: %s", recorder.Code, test.want, recorder.Body.String())
+			}
+		})
+	}
+}
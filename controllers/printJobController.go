@@ -0,0 +1,565 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-restaurant-management/database"
+	"go-restaurant-management/escpos"
+	"go-restaurant-management/models"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	helper "go-restaurant-management/helpers"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PrintRequest struct {
+	Printer_id  *string `json:"printer_id"`  // first active receipt printer when left out
+	Kick_drawer *bool   `json:"kick_drawer"` // opens the drawer by default when the invoice has cash payments
+}
+
+var printJobCollection *mongo.Collection = database.OpenCollection(database.Client, "printJob")
+
+func GetPrintJobs() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := bson.M{}
+
+		if status := c.Query("status"); status != "" {
+			filter["status"] = status
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := printJobCollection.Find(ctx, filter)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving print jobs from database"})
+			return
+		}
+
+		var allPrintJobs []models.PrintJob
+
+		if err := result.All(ctx, &allPrintJobs); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allPrintJobs)
+	}
+}
+
+func GetPrintJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		printJobId := c.Param("print_job_id")
+		var printJob models.PrintJob
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := printJobCollection.FindOne(ctx, bson.M{"print_job_id": printJobId}).Decode(&printJob)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "print job not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, printJob)
+	}
+}
+
+// sends a failed job to its printer again
+func RetryPrintJob() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		printJobId := c.Param("print_job_id")
+		var printJob models.PrintJob
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if err := printJobCollection.FindOne(ctx, bson.M{"print_job_id": printJobId}).Decode(&printJob); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "print job not found"})
+			return
+		}
+
+		if printJob.Status == "PRINTED" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "print job is already printed"})
+			return
+		}
+
+		if err := sendPrintJob(ctx, &printJob); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "print_job": printJob})
+			return
+		}
+
+		c.JSON(http.StatusOK, printJob)
+	}
+}
+
+// prints the customer receipt of an invoice
+func PrintInvoice() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invoiceId := c.Param("invoice_id")
+		var printRequest PrintRequest
+
+		if c.Request.ContentLength > 0 {
+			if err := c.BindJSON(&printRequest); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		invoiceView, err := buildInvoiceView(ctx, invoiceId)
+
+		if err == errInvoiceNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		var printer models.Printer
+		filter := bson.M{"type": "RECEIPT", "active": true}
+
+		if printRequest.Printer_id != nil {
+			filter["printer_id"] = *printRequest.Printer_id
+		}
+
+		if err := printerCollection.FindOne(ctx, filter).Decode(&printer); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "no active receipt printer found"})
+			return
+		}
+
+		layout, err := receiptLayoutFromEnv()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		kickDrawer := false
+
+		for _, payment := range invoiceView.Payments {
+			if *payment.Method == "CASH" {
+				kickDrawer = true
+			}
+		}
+
+		if printRequest.Kick_drawer != nil {
+			kickDrawer = *printRequest.Kick_drawer
+		}
+
+		data := encodeReceipt(receiptRows(layout, invoiceView), printerColumns(printer), invoiceView.Invoice_id, kickDrawer)
+
+		printJob, err := queuePrintJob(ctx, printer, "RECEIPT", invoiceView.Invoice_id, data)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while queuing the print job"})
+			return
+		}
+
+		if err := sendPrintJob(ctx, printJob); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "print_job": printJob})
+			return
+		}
+
+		c.JSON(http.StatusOK, printJob)
+	}
+}
+
+// queues a kitchen ticket for every kitchen printer that prepares some of the
+// new items and sends them in the background, so a printer problem never holds
+// up the order
+func printKitchenTickets(ctx context.Context, order models.Order, orderId string, orderItems []models.OrderItem) error {
+	result, err := printerCollection.Find(ctx, bson.M{"type": "KITCHEN", "active": true})
+
+	if err != nil {
+		return err
+	}
+
+	var printers []models.Printer
+
+	if err := result.All(ctx, &printers); err != nil {
+		return err
+	}
+
+	if len(printers) == 0 {
+		return nil
+	}
+
+	foodIds := []string{}
+
+	for _, orderItem := range orderItems {
+		if orderItem.Food_id != nil {
+			foodIds = append(foodIds, *orderItem.Food_id)
+		}
+	}
+
+	foodResult, err := foodCollection.Find(ctx, bson.M{"food_id": bson.M{"$in": foodIds}})
+
+	if err != nil {
+		return err
+	}
+
+	var foods []models.Food
+
+	if err := foodResult.All(ctx, &foods); err != nil {
+		return err
+	}
+
+	menuIds := []string{}
+
+	for _, food := range foods {
+		menuIds = append(menuIds, *food.Menu_id)
+	}
+
+	menuResult, err := menuCollection.Find(ctx, bson.M{"menu_id": bson.M{"$in": menuIds}})
+
+	if err != nil {
+		return err
+	}
+
+	var menus []models.Menu
+
+	if err := menuResult.All(ctx, &menus); err != nil {
+		return err
+	}
+
+	categories := map[string]string{}
+
+	for _, menu := range menus {
+		categories[menu.Menu_id] = menu.Category
+	}
+
+	foodsById := map[string]models.Food{}
+
+	for _, food := range foods {
+		foodsById[food.Food_id] = food
+	}
+
+	// combo bundle lines have no food of their own, their child items are cooked
+	ticketItems := map[string][]models.OrderItem{}
+
+	for _, orderItem := range orderItems {
+		if orderItem.Food_id == nil {
+			continue
+		}
+
+		food, ok := foodsById[*orderItem.Food_id]
+
+		if !ok {
+			continue
+		}
+
+		for _, printer := range kitchenPrintersFor(printers, food, categories[*food.Menu_id]) {
+			ticketItems[printer.Printer_id] = append(ticketItems[printer.Printer_id], orderItem)
+		}
+	}
+
+	var tableNumber *int
+
+	if order.Table_id != nil {
+		var table models.Table
+
+		if err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_id}).Decode(&table); err == nil {
+			tableNumber = table.Table_number
+		}
+	}
+
+	var printJobs []*models.PrintJob
+
+	for _, printer := range printers {
+		items, ok := ticketItems[printer.Printer_id]
+
+		if !ok {
+			continue
+		}
+
+		data := encodeKitchenTicket(printer, order, orderId, tableNumber, items, foodsById)
+
+		printJob, err := queuePrintJob(ctx, printer, "KITCHEN_TICKET", orderId, data)
+
+		if err != nil {
+			return err
+		}
+
+		printJobs = append(printJobs, printJob)
+	}
+
+	go func() {
+		sendCtx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		for _, printJob := range printJobs {
+			if err := sendPrintJob(sendCtx, printJob); err != nil {
+				log.Println(err)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// a food goes to the printers of its station, else to those of its menu
+// category, else to the kitchen printers that take everything
+func kitchenPrintersFor(printers []models.Printer, food models.Food, category string) []models.Printer {
+	var byStation, byCategory, catchAll []models.Printer
+
+	for _, printer := range printers {
+		if food.Station != nil && containsString(printer.Stations, *food.Station) {
+			byStation = append(byStation, printer)
+		}
+
+		if containsString(printer.Categories, category) {
+			byCategory = append(byCategory, printer)
+		}
+
+		if len(printer.Stations) == 0 && len(printer.Categories) == 0 {
+			catchAll = append(catchAll, printer)
+		}
+	}
+
+	if len(byStation) > 0 {
+		return byStation
+	}
+
+	if len(byCategory) > 0 {
+		return byCategory
+	}
+
+	return catchAll
+}
+
+func encodeReceipt(rows []receiptRow, columns int, invoiceId string, kickDrawer bool) []byte {
+	encoder := escpos.New()
+
+	for _, row := range rows {
+		if row.Rule {
+			encoder.Line(strings.Repeat("-", columns))
+			continue
+		}
+
+		encoder.Bold(row.Bold)
+
+		if row.Centered {
+			encoder.Align(escpos.AlignCenter)
+
+			// double width halves the characters that fit on a line
+			if row.Scale > 1 {
+				encoder.Size(2, 2).Line(fitColumns(row.Left, columns/2)).Size(1, 1)
+			} else {
+				encoder.Line(fitColumns(row.Left, columns))
+			}
+
+			encoder.Align(escpos.AlignLeft)
+		} else if row.Scale > 1 {
+			encoder.Size(1, 2).Line(escpos.Columns(row.Left, row.Right, columns)).Size(1, 1)
+		} else {
+			encoder.Line(escpos.Columns(row.Left, row.Right, columns))
+		}
+
+		encoder.Bold(false)
+	}
+
+	// RECEIPT_QR_URL links the receipt to its online copy, e.g. https://example.com/r/{invoice_id}
+	if url := helper.GetEnvVariable("RECEIPT_QR_URL"); url != "" {
+		encoder.Feed(1).Align(escpos.AlignCenter).QRCode(strings.ReplaceAll(url, "{invoice_id}", invoiceId), 6).Align(escpos.AlignLeft)
+	}
+
+	if kickDrawer {
+		encoder.KickDrawer()
+	}
+
+	return encoder.Feed(4).Cut(true).Bytes()
+}
+
+func encodeKitchenTicket(printer models.Printer, order models.Order, orderId string, tableNumber *int, items []models.OrderItem, foodsById map[string]models.Food) []byte {
+	columns := printerColumns(printer)
+	encoder := escpos.New()
+
+	encoder.Align(escpos.AlignCenter).Bold(true).Size(2, 2)
+
+	if tableNumber != nil {
+		encoder.Line(fmt.Sprintf("TABLE %d", *tableNumber))
+	} else if order.Order_type != nil {
+		encoder.Line(strings.ReplaceAll(*order.Order_type, "_", " "))
+	} else {
+		encoder.Line("ORDER")
+	}
+
+	encoder.Size(1, 1).Bold(false).Align(escpos.AlignLeft)
+	encoder.Line(escpos.Columns(*printer.Name, time.Now().Format("15:04"), columns))
+	encoder.Line(escpos.Columns("Order", orderId, columns))
+	encoder.Line(strings.Repeat("-", columns))
+
+	for _, item := range items {
+		name := *foodsById[*item.Food_id].Name
+
+		encoder.Bold(true).Size(1, 2)
+		encoder.Line(fitColumns(fmt.Sprintf("1x %s (%s)", name, *item.Quantity), columns))
+		encoder.Size(1, 1).Bold(false)
+
+		if item.Parent_order_item_id != nil {
+			encoder.Line("   part of a combo")
+		}
+
+		if item.Seat_number != nil {
+			encoder.Line(fmt.Sprintf("   seat %d", *item.Seat_number))
+		}
+	}
+
+	return encoder.Feed(4).Cut(true).Bytes()
+}
+
+func printerColumns(printer models.Printer) int {
+	if printer.Columns != nil {
+		return *printer.Columns
+	}
+
+	// 80mm paper in the standard font
+	return 42
+}
+
+func fitColumns(text string, columns int) string {
+	runes := []rune(text)
+
+	if len(runes) > columns {
+		runes = runes[:columns]
+	}
+
+	return string(runes)
+}
+
+func queuePrintJob(ctx context.Context, printer models.Printer, jobType string, referenceId string, data []byte) (*models.PrintJob, error) {
+	var printJob models.PrintJob
+	var err error
+
+	printJob.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return nil, err
+	}
+
+	printJob.ID = primitive.NewObjectID()
+	printJob.Print_job_id = printJob.ID.Hex()
+	printJob.Printer_id = printer.Printer_id
+	printJob.Type = jobType
+	printJob.Reference_id = referenceId
+	printJob.Data = data
+	printJob.Status = "PENDING"
+
+	if _, err := printJobCollection.InsertOne(ctx, printJob); err != nil {
+		return nil, err
+	}
+
+	return &printJob, nil
+}
+
+// writes the job to its printer over raw TCP and records the outcome
+func sendPrintJob(ctx context.Context, printJob *models.PrintJob) error {
+	var printer models.Printer
+	var found *models.Printer
+
+	if err := printerCollection.FindOne(ctx, bson.M{"printer_id": printJob.Printer_id}).Decode(&printer); err == nil {
+		found = &printer
+	}
+
+	sendErr := attemptPrintJob(found, printJob)
+
+	_, err := printJobCollection.UpdateOne(
+		ctx,
+		bson.M{"print_job_id": printJob.Print_job_id},
+		bson.D{
+			{Key: "$set", Value: bson.D{
+				{Key: "status", Value: printJob.Status},
+				{Key: "attempts", Value: printJob.Attempts},
+				{Key: "error", Value: printJob.Error},
+				{Key: "printed_at", Value: printJob.Printed_at},
+			}},
+		},
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return sendErr
+}
+
+// writes the job to the printer, nil when it was not found, and sets the
+// job's status from the outcome
+func attemptPrintJob(printer *models.Printer, printJob *models.PrintJob) error {
+	var sendErr error
+
+	if printer == nil || printer.Address == nil {
+		sendErr = errors.New("printer not found")
+	} else {
+		sendErr = writeToPrinter(*printer.Address, printJob.Data)
+	}
+
+	printJob.Attempts++
+	printJob.Status = "PRINTED"
+	printJob.Error = ""
+
+	if sendErr != nil {
+		printJob.Status = "FAILED"
+		printJob.Error = sendErr.Error()
+		return sendErr
+	}
+
+	printedAt, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return err
+	}
+
+	printJob.Printed_at = &printedAt
+
+	return nil
+}
+
+func writeToPrinter(address string, data []byte) error {
+	// printers listen on the raw printing port unless told otherwise
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "9100")
+	}
+
+	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
+
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return err
+	}
+
+	_, err = conn.Write(data)
+
+	return err
+}
@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"bytes"
+	"go-restaurant-management/escpos"
+	"go-restaurant-management/models"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// listens like a network printer and hands back everything written to it
+func testPrinter(t *testing.T) (string, <-chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan []byte, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+
+	return listener.Addr().String(), received
+}
+
+func receivedData(t *testing.T, received <-chan []byte) []byte {
+	select {
+	case data := <-received:
+		return data
+	case <-time.After(5 * time.Second):
+		t.Fatal("the printer received nothing")
+		return nil
+	}
+}
+
+func TestWriteToPrinter(t *testing.T) {
+	address, received := testPrinter(t)
+	data := escpos.New().Line("hello").Cut(false).Bytes()
+
+	if err := writeToPrinter(address, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := receivedData(t, received); !bytes.Equal(got, data) {
+		t.Errorf("printer received % x, want % x", got, data)
+	}
+}
+
+func TestWriteToPrinterUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := listener.Addr().String()
+	listener.Close()
+
+	if err := writeToPrinter(address, []byte("x")); err == nil {
+		t.Error("expected an error writing to a closed port")
+	}
+}
+
+func TestAttemptPrintJob(t *testing.T) {
+	address, received := testPrinter(t)
+	printer := models.Printer{Address: &address}
+	printJob := models.PrintJob{Data: []byte("ticket"), Status: "PENDING"}
+
+	if err := attemptPrintJob(&printer, &printJob); err != nil {
+		t.Fatal(err)
+	}
+
+	if printJob.Status != "PRINTED" || printJob.Attempts != 1 || printJob.Printed_at == nil || printJob.Error != "" {
+		t.Errorf("unexpected job after printing: %+v", printJob)
+	}
+
+	if got := receivedData(t, received); string(got) != "ticket" {
+		t.Errorf("printer received %q", got)
+	}
+}
+
+func TestAttemptPrintJobFailures(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed := listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name    string
+		printer *models.Printer
+	}{
+		{"printer not found", nil},
+		{"printer without an address", &models.Printer{}},
+		{"printer unreachable", &models.Printer{Address: &closed}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			printJob := models.PrintJob{Data: []byte("ticket"), Status: "PENDING", Attempts: 2}
+
+			if err := attemptPrintJob(test.printer, &printJob); err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if printJob.Status != "FAILED" || printJob.Attempts != 3 || printJob.Error == "" || printJob.Printed_at != nil {
+				t.Errorf("unexpected job after failing: %+v", printJob)
+			}
+		})
+	}
+}
+
+func TestEncodeKitchenTicket(t *testing.T) {
+	name := "Grill"
+	columns := 24
+	printer := models.Printer{Name: &name, Columns: &columns}
+
+	foodId := "food1"
+	foodName := "Satay"
+	size := "LARGE"
+	seat := 2
+	table := 7
+	items := []models.OrderItem{{Food_id: &foodId, Quantity: &size, Seat_number: &seat}}
+	foods := map[string]models.Food{foodId: {Name: &foodName}}
+
+	data := encodeKitchenTicket(printer, models.Order{}, "order1", &table, items, foods)
+
+	for _, want := range []string{"TABLE 7\n", "Grill", "Order             order1\n", "1x Satay (LARGE)\n", "   seat 2\n"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("ticket does not contain %q:\n%q", want, data)
+		}
+	}
+
+	if !bytes.HasSuffix(data, []byte{0x1d, 'V', 66, 3}) {
+		t.Error("ticket does not end with a cut")
+	}
+}
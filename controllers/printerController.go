@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var printerCollection *mongo.Collection = database.OpenCollection(database.Client, "printer")
+
+func GetPrinters() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := printerCollection.Find(ctx, bson.M{})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving printers from database"})
+			return
+		}
+
+		var allPrinters []bson.M
+
+		if err := result.All(ctx, &allPrinters); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allPrinters)
+	}
+}
+
+func GetPrinter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		printerId := c.Param("printer_id")
+		var printer models.Printer
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := printerCollection.FindOne(ctx, bson.M{"printer_id": printerId}).Decode(&printer)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "printer not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, printer)
+	}
+}
+
+func CreatePrinter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var printer models.Printer
+
+		if err := c.BindJSON(&printer); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(printer)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		var err error
+
+		printer.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+			return
+		}
+
+		printer.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		printer.ID = primitive.NewObjectID()
+		printer.Printer_id = printer.ID.Hex()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, insertErr := printerCollection.InsertOne(ctx, printer)
+		defer cancel()
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "printer is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdatePrinter() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var printer models.Printer
+
+		if err := c.BindJSON(&printer); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		printerId := c.Param("printer_id")
+		var updateObj primitive.D
+
+		if printer.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: printer.Name})
+		}
+
+		if printer.Address != nil {
+			if err := validate.Var(printer.Address, "hostname_port|hostname|ip"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "address", Value: printer.Address})
+		}
+
+		if printer.Type != nil {
+			if err := validate.Var(printer.Type, "eq=RECEIPT|eq=KITCHEN"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "type", Value: printer.Type})
+		}
+
+		if printer.Categories != nil {
+			updateObj = append(updateObj, bson.E{Key: "categories", Value: printer.Categories})
+		}
+
+		if printer.Stations != nil {
+			updateObj = append(updateObj, bson.E{Key: "stations", Value: printer.Stations})
+		}
+
+		if printer.Columns != nil {
+			if err := validate.Var(printer.Columns, "gte=24,lte=64"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "columns", Value: printer.Columns})
+		}
+
+		if printer.Active != nil {
+			updateObj = append(updateObj, bson.E{Key: "active", Value: printer.Active})
+		}
+
+		var err error
+		printer.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: printer.Updated_at})
+
+		filter := bson.M{"printer_id": printerId}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := printerCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: updateObj},
+			},
+		)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "printer updated failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "printer not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
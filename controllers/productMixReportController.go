@@ -0,0 +1,482 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type FoodSales struct {
+	Rank          int     `json:"rank"`
+	Food_id       string  `json:"food_id"`
+	Name          string  `json:"name"`
+	Menu          string  `json:"menu"`
+	Category      string  `json:"category"`
+	Items_sold    int     `json:"items_sold"`
+	Orders        int     `json:"orders"`
+	Revenue       float64 `json:"revenue"`
+	Revenue_share float64 `json:"revenue_share"`
+}
+
+type FoodAttachment struct {
+	Food_id          string  `json:"food_id"`
+	Name             string  `json:"name"`
+	With_food_id     string  `json:"with_food_id"`
+	With_name        string  `json:"with_name"`
+	Orders_together  int     `json:"orders_together"`
+	Orders_with_food int     `json:"orders_with_food"`
+	Attach_rate      float64 `json:"attach_rate"` // percentage of the food's orders that also had the other food
+}
+
+var foodSalesHeader = []string{"rank", "food_id", "name", "menu", "category", "items_sold", "orders", "revenue", "revenue_share"}
+
+// top N foods by items sold (?by=quantity, the default) or by revenue (?by=revenue)
+func GetBestSellersReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rankedFoodSales(c, "best-sellers", false)
+	}
+}
+
+// bottom N foods, counting foods that did not sell at all in the range
+func GetSlowMoversReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rankedFoodSales(c, "slow-movers", true)
+	}
+}
+
+func rankedFoodSales(c *gin.Context, report string, slowest bool) {
+	startDate, endDate, err := dateRange(c)
+
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	by := c.DefaultQuery("by", "quantity")
+
+	if by != "quantity" && by != "revenue" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "by must be quantity or revenue"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.Query("limit"))
+
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+	defer cancel()
+
+	sales, err := foodSales(ctx, c, startDate, endDate)
+
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating food sales"})
+		return
+	}
+
+	if slowest {
+		sales, err = withUnsoldFoods(ctx, c, sales)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving foods from database"})
+			return
+		}
+	}
+
+	totalRevenue := 0.0
+
+	for _, food := range sales {
+		totalRevenue += food.Revenue
+	}
+
+	// ties on the chosen measure are broken by the other one
+	sort.SliceStable(sales, func(i, j int) bool {
+		a, b := sales[i], sales[j]
+
+		if slowest {
+			a, b = b, a
+		}
+
+		if by == "revenue" && a.Revenue != b.Revenue {
+			return a.Revenue > b.Revenue
+		}
+
+		if a.Items_sold != b.Items_sold {
+			return a.Items_sold > b.Items_sold
+		}
+
+		return a.Revenue > b.Revenue
+	})
+
+	if len(sales) > limit {
+		sales = sales[:limit]
+	}
+
+	for i, food := range sales {
+		food.Rank = i + 1
+		food.Revenue_share = percentage(food.Revenue, totalRevenue)
+	}
+
+	if wantsCSV(c) {
+		rows := [][]string{}
+
+		for _, food := range sales {
+			rows = append(rows, []string{
+				strconv.Itoa(food.Rank),
+				food.Food_id,
+				food.Name,
+				food.Menu,
+				food.Category,
+				strconv.Itoa(food.Items_sold),
+				strconv.Itoa(food.Orders),
+				strconv.FormatFloat(food.Revenue, 'f', 2, 64),
+				strconv.FormatFloat(food.Revenue_share, 'f', 2, 64),
+			})
+		}
+
+		writeCSV(c, report+"-"+reportPeriod(startDate, endDate)+".csv", foodSalesHeader, rows)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"start_date":    startDate.Format("2006-01-02"),
+		"end_date":      endDate.AddDate(0, 0, -1).Format("2006-01-02"),
+		"by":            by,
+		"total_revenue": toFixed(totalRevenue, 2),
+		"foods":         sales,
+	})
+}
+
+// items sold, orders and revenue per food, combo components included
+func foodSales(ctx context.Context, c *gin.Context, startDate time.Time, endDate time.Time) ([]*FoodSales, error) {
+	pipeline := salesPipeline(c, startDate, endDate, c.Query("food_id"))
+
+	groupStage := bson.D{
+		{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$food_id"},
+			{Key: "name", Value: bson.D{{Key: "$first", Value: "$food.name"}}},
+			{Key: "menu", Value: bson.D{{Key: "$first", Value: "$menu.name"}}},
+			{Key: "category", Value: bson.D{{Key: "$first", Value: "$menu.category"}}},
+			{Key: "items_sold", Value: bson.D{{Key: "$sum", Value: 1}}},
+			{Key: "orders", Value: bson.D{{Key: "$addToSet", Value: "$order_id"}}},
+			{Key: "revenue", Value: bson.D{{Key: "$sum", Value: "$unit_price"}}},
+		}},
+	}
+
+	projectStage := bson.D{
+		{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "food_id", Value: "$_id"},
+			{Key: "name", Value: 1},
+			{Key: "menu", Value: 1},
+			{Key: "category", Value: 1},
+			{Key: "items_sold", Value: 1},
+			{Key: "orders", Value: bson.D{{Key: "$size", Value: "$orders"}}},
+			{Key: "revenue", Value: bson.D{{Key: "$round", Value: bson.A{"$revenue", 2}}}},
+		}},
+	}
+
+	pipeline = append(pipeline,
+		// combo bundles have no food of their own
+		bson.D{{Key: "$match", Value: bson.D{{Key: "food_id", Value: bson.D{{Key: "$ne", Value: nil}}}}}},
+		groupStage,
+		projectStage,
+	)
+
+	result, err := orderItemCollection.Aggregate(ctx, pipeline)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sales := []*FoodSales{}
+
+	if err := result.All(ctx, &sales); err != nil {
+		return nil, err
+	}
+
+	return sales, nil
+}
+
+// adds the foods matching the request's menu, category and food filters that
+// were not sold at all, so the slowest movers are not missed
+func withUnsoldFoods(ctx context.Context, c *gin.Context, sales []*FoodSales) ([]*FoodSales, error) {
+	sold := map[string]bool{}
+
+	for _, food := range sales {
+		sold[food.Food_id] = true
+	}
+
+	menus := map[string]models.Menu{}
+	menuIds := []string{}
+
+	result, err := menuCollection.Find(ctx, bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var allMenus []models.Menu
+
+	if err := result.All(ctx, &allMenus); err != nil {
+		return nil, err
+	}
+
+	for _, menu := range allMenus {
+		menus[menu.Menu_id] = menu
+
+		if menu.Category == c.Query("category") {
+			menuIds = append(menuIds, menu.Menu_id)
+		}
+	}
+
+	foodFilter := bson.M{}
+
+	if foodId := c.Query("food_id"); foodId != "" {
+		foodFilter["food_id"] = foodId
+	}
+
+	if menuId := c.Query("menu_id"); menuId != "" {
+		foodFilter["menu_id"] = menuId
+	}
+
+	// kept apart from menu_id so that both filters apply
+	if c.Query("category") != "" {
+		foodFilter["$and"] = bson.A{bson.M{"menu_id": bson.M{"$in": menuIds}}}
+	}
+
+	result, err = foodCollection.Find(ctx, foodFilter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var foods []models.Food
+
+	if err := result.All(ctx, &foods); err != nil {
+		return nil, err
+	}
+
+	for _, food := range foods {
+		if sold[food.Food_id] {
+			continue
+		}
+
+		menu := menus[*food.Menu_id]
+
+		sales = append(sales, &FoodSales{
+			Food_id:  food.Food_id,
+			Name:     *food.Name,
+			Menu:     menu.Name,
+			Category: menu.Category,
+		})
+	}
+
+	return sales, nil
+}
+
+// how often food Y is ordered along with food X, as a share of the orders
+// containing X; ?food_id narrows it down to one food. With a menu or category
+// filter both foods have to be on it.
+func GetAttachRateReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startDate, endDate, err := dateRange(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		limit, err := strconv.Atoi(c.Query("limit"))
+
+		if err != nil || limit < 1 {
+			limit = 10
+		}
+
+		// a food ordered once with something else attaches 100%, so rare foods can be left out
+		minOrders, err := strconv.Atoi(c.Query("min_orders"))
+
+		if err != nil || minOrders < 1 {
+			minOrders = 1
+		}
+
+		foodId := c.Query("food_id")
+
+		// every food has to stay in for the orders to show what went with what
+		pipeline := salesPipeline(c, startDate, endDate, "")
+
+		pipeline = append(pipeline,
+			bson.D{{Key: "$match", Value: bson.D{{Key: "food_id", Value: bson.D{{Key: "$ne", Value: nil}}}}}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$order_id"},
+				{Key: "foods", Value: bson.D{{Key: "$addToSet", Value: "$food_id"}}},
+			}}},
+			bson.D{{Key: "$project", Value: bson.D{
+				{Key: "foods", Value: 1},
+				{Key: "food", Value: "$foods"},
+			}}},
+			bson.D{{Key: "$unwind", Value: "$food"}},
+		)
+
+		if foodId != "" {
+			pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.D{{Key: "food", Value: foodId}}}})
+		}
+
+		// pairing a food with itself counts the orders it appeared in
+		pipeline = append(pipeline,
+			bson.D{{Key: "$unwind", Value: "$foods"}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{
+					{Key: "food", Value: "$food"},
+					{Key: "with", Value: "$foods"},
+				}},
+				{Key: "orders", Value: bson.D{{Key: "$sum", Value: 1}}},
+			}}},
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := orderItemCollection.Aggregate(ctx, pipeline)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating order items"})
+			return
+		}
+
+		var pairs []struct {
+			ID struct {
+				Food string `bson:"food"`
+				With string `bson:"with"`
+			} `bson:"_id"`
+			Orders int `bson:"orders"`
+		}
+
+		if err := result.All(ctx, &pairs); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		ordersWithFood := map[string]int{}
+		foodIds := []string{}
+
+		for _, pair := range pairs {
+			if pair.ID.Food == pair.ID.With {
+				ordersWithFood[pair.ID.Food] = pair.Orders
+				foodIds = append(foodIds, pair.ID.Food)
+			}
+		}
+
+		names, err := foodNames(ctx, foodIds)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving foods from database"})
+			return
+		}
+
+		attachments := []FoodAttachment{}
+
+		for _, pair := range pairs {
+			if pair.ID.Food == pair.ID.With || ordersWithFood[pair.ID.Food] < minOrders {
+				continue
+			}
+
+			attachments = append(attachments, FoodAttachment{
+				Food_id:          pair.ID.Food,
+				Name:             names[pair.ID.Food],
+				With_food_id:     pair.ID.With,
+				With_name:        names[pair.ID.With],
+				Orders_together:  pair.Orders,
+				Orders_with_food: ordersWithFood[pair.ID.Food],
+				Attach_rate:      percentage(float64(pair.Orders), float64(ordersWithFood[pair.ID.Food])),
+			})
+		}
+
+		sort.SliceStable(attachments, func(i, j int) bool {
+			if attachments[i].Attach_rate != attachments[j].Attach_rate {
+				return attachments[i].Attach_rate > attachments[j].Attach_rate
+			}
+
+			if attachments[i].Orders_together != attachments[j].Orders_together {
+				return attachments[i].Orders_together > attachments[j].Orders_together
+			}
+
+			return attachments[i].Food_id+attachments[i].With_food_id < attachments[j].Food_id+attachments[j].With_food_id
+		})
+
+		if len(attachments) > limit {
+			attachments = attachments[:limit]
+		}
+
+		if wantsCSV(c) {
+			rows := [][]string{}
+
+			for _, attachment := range attachments {
+				rows = append(rows, []string{
+					attachment.Food_id,
+					attachment.Name,
+					attachment.With_food_id,
+					attachment.With_name,
+					strconv.Itoa(attachment.Orders_together),
+					strconv.Itoa(attachment.Orders_with_food),
+					strconv.FormatFloat(attachment.Attach_rate, 'f', 2, 64),
+				})
+			}
+
+			header := []string{"food_id", "name", "with_food_id", "with_name", "orders_together", "orders_with_food", "attach_rate"}
+
+			writeCSV(c, "attach-rate-"+reportPeriod(startDate, endDate)+".csv", header, rows)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"start_date":  startDate.Format("2006-01-02"),
+			"end_date":    endDate.AddDate(0, 0, -1).Format("2006-01-02"),
+			"attachments": attachments,
+		})
+	}
+}
+
+func foodNames(ctx context.Context, foodIds []string) (map[string]string, error) {
+	names := map[string]string{}
+
+	if len(foodIds) == 0 {
+		return names, nil
+	}
+
+	result, err := foodCollection.Find(ctx, bson.M{"food_id": bson.M{"$in": foodIds}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var foods []models.Food
+
+	if err := result.All(ctx, &foods); err != nil {
+		return nil, err
+	}
+
+	for _, food := range foods {
+		names[food.Food_id] = *food.Name
+	}
+
+	return names, nil
+}
+
+// the inclusive date range of a report, as used in export file names
+func reportPeriod(startDate time.Time, endDate time.Time) string {
+	return startDate.Format("2006-01-02") + "_" + endDate.AddDate(0, 0, -1).Format("2006-01-02")
+}
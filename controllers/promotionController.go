@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	helper "go-restaurant-management/helpers"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var promotionCollection *mongo.Collection = database.OpenCollection(database.Client, "promotion")
+
+func GetPromotions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := promotionCollection.Find(ctx, bson.M{})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving promotions from database"})
+			return
+		}
+
+		var allPromotions []bson.M
+
+		if err := result.All(ctx, &allPromotions); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allPromotions)
+	}
+}
+
+func GetPromotion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		promotionId := c.Param("promotion_id")
+		var promotion models.Promotion
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := promotionCollection.FindOne(ctx, bson.M{"promotion_id": promotionId}).Decode(&promotion)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "promotion not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, promotion)
+	}
+}
+
+func CreatePromotion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// promotions discount every invoice, so like manual discounts they are for managers
+		if err := helper.CheckUserType(c, "MANAGER"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		var promotion models.Promotion
+
+		if err := c.BindJSON(&promotion); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(promotion)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if promotion.Code != nil {
+			count, err := promotionCollection.CountDocuments(ctx, bson.M{"code": promotion.Code})
+
+			if err != nil {
+				log.Println(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking the voucher code"})
+				return
+			}
+
+			if count > 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "this voucher code already exists"})
+				return
+			}
+		}
+
+		if promotion.Active == nil {
+			active := true
+			promotion.Active = &active
+		}
+
+		promotion.Usage_count = 0
+
+		var err error
+
+		promotion.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+			return
+		}
+
+		promotion.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		promotion.ID = primitive.NewObjectID()
+		promotion.Promotion_id = promotion.ID.Hex()
+
+		result, insertErr := promotionCollection.InsertOne(ctx, promotion)
+
+		// the unique index on code catches two promotions created with the same code at once
+		if mongo.IsDuplicateKeyError(insertErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "this voucher code already exists"})
+			return
+		}
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "promotion is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdatePromotion() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// promotions discount every invoice, so like manual discounts they are for managers
+		if err := helper.CheckUserType(c, "MANAGER"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		var promotion models.Promotion
+
+		if err := c.BindJSON(&promotion); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		promotionId := c.Param("promotion_id")
+		var updateObj primitive.D
+
+		if promotion.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: promotion.Name})
+		}
+
+		if promotion.Value != nil {
+			if err := validate.Var(promotion.Value, "gt=0"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "value", Value: promotion.Value})
+		}
+
+		if promotion.Food_ids != nil {
+			updateObj = append(updateObj, bson.E{Key: "food_ids", Value: promotion.Food_ids})
+		}
+
+		if promotion.Min_subtotal != nil {
+			updateObj = append(updateObj, bson.E{Key: "min_subtotal", Value: promotion.Min_subtotal})
+		}
+
+		if promotion.Usage_limit != nil {
+			updateObj = append(updateObj, bson.E{Key: "usage_limit", Value: promotion.Usage_limit})
+		}
+
+		if promotion.Starts_at != nil {
+			updateObj = append(updateObj, bson.E{Key: "starts_at", Value: promotion.Starts_at})
+		}
+
+		if promotion.Expires_at != nil {
+			updateObj = append(updateObj, bson.E{Key: "expires_at", Value: promotion.Expires_at})
+		}
+
+		if promotion.Active != nil {
+			updateObj = append(updateObj, bson.E{Key: "active", Value: promotion.Active})
+		}
+
+		var err error
+		promotion.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: promotion.Updated_at})
+
+		filter := bson.M{"promotion_id": promotionId}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := promotionCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: updateObj},
+			},
+		)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "promotion updated failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "promotion not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func promotionIsValid(promotion models.Promotion, now time.Time) bool {
+	if promotion.Active != nil && !*promotion.Active {
+		return false
+	}
+
+	if promotion.Starts_at != nil && now.Before(*promotion.Starts_at) {
+		return false
+	}
+
+	if promotion.Expires_at != nil && now.After(*promotion.Expires_at) {
+		return false
+	}
+
+	if promotion.Usage_limit != nil && promotion.Usage_count >= *promotion.Usage_limit {
+		return false
+	}
+
+	return true
+}
+
+// automatic promotions running now, plus the promotion of the voucher code if one is given
+func eligiblePromotions(ctx context.Context, voucherCode *string) ([]models.Promotion, error) {
+	now := time.Now()
+
+	result, err := promotionCollection.Find(ctx, bson.M{"code": nil})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var automatic []models.Promotion
+
+	if err := result.All(ctx, &automatic); err != nil {
+		return nil, err
+	}
+
+	promotions := []models.Promotion{}
+
+	for _, promotion := range automatic {
+		if promotionIsValid(promotion, now) {
+			promotions = append(promotions, promotion)
+		}
+	}
+
+	if voucherCode == nil || *voucherCode == "" {
+		return promotions, nil
+	}
+
+	var voucher models.Promotion
+
+	if err := promotionCollection.FindOne(ctx, bson.M{"code": voucherCode}).Decode(&voucher); err != nil {
+		return nil, errors.New("voucher code not found")
+	}
+
+	if !promotionIsValid(voucher, now) {
+		return nil, errors.New("voucher code is expired or used up")
+	}
+
+	return append(promotions, voucher), nil
+}
+
+// counts a use of the voucher, failing when another invoice took the last one
+func redeemVoucher(ctx context.Context, promotionId string) error {
+	filter := bson.M{"promotion_id": promotionId}
+
+	var promotion models.Promotion
+
+	if err := promotionCollection.FindOne(ctx, filter).Decode(&promotion); err != nil {
+		return err
+	}
+
+	if promotion.Usage_limit != nil {
+		filter["usage_count"] = bson.M{"$lt": *promotion.Usage_limit}
+	}
+
+	result, err := promotionCollection.UpdateOne(ctx, filter, bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "usage_count", Value: 1}}},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return errors.New("voucher code is used up")
+	}
+
+	return nil
+}
@@ -0,0 +1,635 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ReceivedLine struct {
+	Ingredient_id *string  `json:"ingredient_id" validate:"required"`
+	Quantity      *float64 `json:"quantity" validate:"required,gt=0"`
+}
+
+type ReceivedGoods struct {
+	Lines []ReceivedLine `json:"lines" validate:"required,min=1,dive"`
+}
+
+type SuggestedLine struct {
+	Ingredient_id       string  `json:"ingredient_id"`
+	Name                string  `json:"name"`
+	Unit                string  `json:"unit"`
+	Quantity_on_hand    float64 `json:"quantity_on_hand"`
+	Par_level           float64 `json:"par_level"`
+	Average_daily_usage float64 `json:"average_daily_usage"`
+	Quantity            float64 `json:"quantity"`
+	Unit_cost           float64 `json:"unit_cost"`
+}
+
+type SuggestedPurchaseOrder struct {
+	Supplier_id       string          `json:"supplier_id"`
+	Lines             []SuggestedLine `json:"lines"`
+	Purchase_order_id string          `json:"purchase_order_id,omitempty"`
+}
+
+var purchaseOrderCollection *mongo.Collection = database.OpenCollection(database.Client, "purchaseOrder")
+
+func GetPurchaseOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := bson.M{}
+
+		if status := c.Query("status"); status != "" {
+			filter["status"] = status
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := purchaseOrderCollection.Find(ctx, filter)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving purchase orders from database"})
+			return
+		}
+
+		var allPurchaseOrders []bson.M
+
+		if err := result.All(ctx, &allPurchaseOrders); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allPurchaseOrders)
+	}
+}
+
+func GetPurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		purchaseOrderId := c.Param("purchase_order_id")
+		var purchaseOrder models.PurchaseOrder
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := purchaseOrderCollection.FindOne(ctx, bson.M{"purchase_order_id": purchaseOrderId}).Decode(&purchaseOrder)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "purchase order not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, purchaseOrder)
+	}
+}
+
+func CreatePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var purchaseOrder models.PurchaseOrder
+		var supplier models.Supplier
+
+		if err := c.BindJSON(&purchaseOrder); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// every purchase order starts as a draft
+		purchaseOrder.Status = "DRAFT"
+
+		for i := range purchaseOrder.Lines {
+			purchaseOrder.Lines[i].Received_quantity = 0
+		}
+
+		validationError := validate.Struct(purchaseOrder)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		err := supplierCollection.FindOne(ctx, bson.M{"supplier_id": purchaseOrder.Supplier_id}).Decode(&supplier)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "supplier not found"})
+			return
+		}
+
+		if err := checkPurchaseOrderIngredients(ctx, purchaseOrder.Lines); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, insertErr := insertPurchaseOrder(ctx, &purchaseOrder)
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "purchase order is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdatePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var purchaseOrder models.PurchaseOrder
+		var foundPurchaseOrder models.PurchaseOrder
+
+		if err := c.BindJSON(&purchaseOrder); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		purchaseOrderId := c.Param("purchase_order_id")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		err := purchaseOrderCollection.FindOne(ctx, bson.M{"purchase_order_id": purchaseOrderId}).Decode(&foundPurchaseOrder)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "purchase order not found"})
+			return
+		}
+
+		// only drafts can be edited, status changes go through send and receive
+		if foundPurchaseOrder.Status != "DRAFT" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only draft purchase orders can be updated"})
+			return
+		}
+
+		var updateObj primitive.D
+
+		if purchaseOrder.Supplier_id != nil {
+			var supplier models.Supplier
+
+			err := supplierCollection.FindOne(ctx, bson.M{"supplier_id": purchaseOrder.Supplier_id}).Decode(&supplier)
+
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "supplier not found"})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "supplier_id", Value: purchaseOrder.Supplier_id})
+		}
+
+		if purchaseOrder.Lines != nil {
+			if err := validate.Var(purchaseOrder.Lines, "min=1,dive"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := checkPurchaseOrderIngredients(ctx, purchaseOrder.Lines); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "lines", Value: purchaseOrder.Lines})
+		}
+
+		purchaseOrder.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: purchaseOrder.Updated_at})
+
+		result, err := purchaseOrderCollection.UpdateOne(
+			ctx,
+			bson.M{"purchase_order_id": purchaseOrderId},
+			bson.D{
+				{Key: "$set", Value: updateObj},
+			},
+		)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "purchase order updated failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func SendPurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		purchaseOrderId := c.Param("purchase_order_id")
+
+		sent_at, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing sent_at"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := purchaseOrderCollection.UpdateOne(
+			ctx,
+			bson.M{"purchase_order_id": purchaseOrderId, "status": "DRAFT"},
+			bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "status", Value: "SENT"},
+					{Key: "sent_at", Value: sent_at},
+					{Key: "updated_at", Value: sent_at},
+				}},
+			},
+		)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "purchase order send failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "draft purchase order not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func ReceivePurchaseOrder() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		purchaseOrderId := c.Param("purchase_order_id")
+		var receivedGoods ReceivedGoods
+		var purchaseOrder models.PurchaseOrder
+
+		if err := c.BindJSON(&receivedGoods); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(receivedGoods)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		err := purchaseOrderCollection.FindOne(ctx, bson.M{"purchase_order_id": purchaseOrderId}).Decode(&purchaseOrder)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "purchase order not found"})
+			return
+		}
+
+		if purchaseOrder.Status != "SENT" && purchaseOrder.Status != "PARTIALLY_RECEIVED" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only sent purchase orders can be received"})
+			return
+		}
+
+		// the receipt only applies to the lines as they were read here
+		filter := bson.M{
+			"purchase_order_id": purchaseOrderId,
+			"status":            purchaseOrder.Status,
+		}
+
+		for i, line := range purchaseOrder.Lines {
+			filter["lines."+strconv.Itoa(i)+".received_quantity"] = line.Received_quantity
+		}
+
+		received := map[string]float64{}
+
+		for _, receivedLine := range receivedGoods.Lines {
+			found := false
+
+			for i, line := range purchaseOrder.Lines {
+				if *line.Ingredient_id == *receivedLine.Ingredient_id {
+					purchaseOrder.Lines[i].Received_quantity += *receivedLine.Quantity
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "ingredient " + *receivedLine.Ingredient_id + " is not on this purchase order"})
+				return
+			}
+
+			received[*receivedLine.Ingredient_id] += *receivedLine.Quantity
+		}
+
+		for _, line := range purchaseOrder.Lines {
+			if line.Received_quantity > *line.Quantity {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "ingredient " + *line.Ingredient_id + " would be received beyond the ordered quantity"})
+				return
+			}
+		}
+
+		now, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		status := "RECEIVED"
+
+		for _, line := range purchaseOrder.Lines {
+			if line.Received_quantity < *line.Quantity {
+				status = "PARTIALLY_RECEIVED"
+			}
+		}
+
+		updateObj := primitive.D{
+			{Key: "lines", Value: purchaseOrder.Lines},
+			{Key: "status", Value: status},
+			{Key: "updated_at", Value: now},
+		}
+
+		if status == "RECEIVED" {
+			updateObj = append(updateObj, bson.E{Key: "received_at", Value: now})
+		}
+
+		var result *mongo.UpdateResult
+
+		// the purchase order and the stock change together
+		err = database.Transaction(ctx, func(ctx context.Context) error {
+			var err error
+
+			result, err = purchaseOrderCollection.UpdateOne(
+				ctx,
+				filter,
+				bson.D{
+					{Key: "$set", Value: updateObj},
+				},
+			)
+
+			if err != nil {
+				return err
+			}
+
+			if result.MatchedCount == 0 {
+				return errPurchaseOrderChanged
+			}
+
+			return adjustStock(ctx, received, 1)
+		})
+
+		if err == errPurchaseOrderChanged {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "purchase order receive failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+var errPurchaseOrderChanged = errors.New("purchase order was received by someone else meanwhile, please retry")
+
+func GetSuggestedPurchaseOrders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// average consumption over the last N days
+		days, err := strconv.Atoi(c.Query("days"))
+
+		if err != nil || days < 1 {
+			days = 14
+		}
+
+		// restock enough to cover this many days of average consumption
+		coverDays, err := strconv.Atoi(c.Query("cover_days"))
+
+		if err != nil || coverDays < 1 {
+			coverDays = 7
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		consumption, err := ingredientConsumption(ctx, time.Now().AddDate(0, 0, -days))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while computing ingredient consumption"})
+			return
+		}
+
+		result, err := ingredientCollection.Find(ctx, bson.M{})
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving ingredients from database"})
+			return
+		}
+
+		var ingredients []models.Ingredient
+
+		if err := result.All(ctx, &ingredients); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		suggestions := []SuggestedPurchaseOrder{}
+		bySupplier := map[string]int{}
+
+		for _, ingredient := range ingredients {
+			averageDailyUsage := consumption[ingredient.Ingredient_id] / float64(days)
+			target := averageDailyUsage * float64(coverDays)
+
+			if ingredient.Par_level != nil {
+				target = math.Max(target, *ingredient.Par_level)
+			}
+
+			quantity := toFixed(target-*ingredient.Quantity_on_hand, 2)
+
+			if quantity <= 0 {
+				continue
+			}
+
+			line := SuggestedLine{
+				Ingredient_id:       ingredient.Ingredient_id,
+				Name:                *ingredient.Name,
+				Unit:                *ingredient.Unit,
+				Quantity_on_hand:    *ingredient.Quantity_on_hand,
+				Average_daily_usage: toFixed(averageDailyUsage, 2),
+				Quantity:            quantity,
+			}
+
+			if ingredient.Par_level != nil {
+				line.Par_level = *ingredient.Par_level
+			}
+
+			if ingredient.Unit_cost != nil {
+				line.Unit_cost = *ingredient.Unit_cost
+			}
+
+			// ingredients without a supplier are grouped under an empty supplier_id
+			supplierId := ""
+
+			if ingredient.Supplier_id != nil {
+				supplierId = *ingredient.Supplier_id
+			}
+
+			index, ok := bySupplier[supplierId]
+
+			if !ok {
+				index = len(suggestions)
+				bySupplier[supplierId] = index
+				suggestions = append(suggestions, SuggestedPurchaseOrder{Supplier_id: supplierId})
+			}
+
+			suggestions[index].Lines = append(suggestions[index].Lines, line)
+		}
+
+		// create=true saves the suggestions as draft purchase orders
+		if c.Query("create") == "true" {
+			for i, suggestion := range suggestions {
+				if suggestion.Supplier_id == "" {
+					continue
+				}
+
+				supplierId := suggestion.Supplier_id
+				purchaseOrder := models.PurchaseOrder{
+					Supplier_id: &supplierId,
+					Status:      "DRAFT",
+				}
+
+				for _, line := range suggestion.Lines {
+					ingredientId := line.Ingredient_id
+					quantity := line.Quantity
+					unitCost := line.Unit_cost
+
+					purchaseOrder.Lines = append(purchaseOrder.Lines, models.PurchaseOrderLine{
+						Ingredient_id: &ingredientId,
+						Quantity:      &quantity,
+						Unit_cost:     &unitCost,
+					})
+				}
+
+				if _, err := insertPurchaseOrder(ctx, &purchaseOrder); err != nil {
+					log.Println(err)
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "purchase order is not created due to some errors"})
+					return
+				}
+
+				suggestions[i].Purchase_order_id = purchaseOrder.Purchase_order_id
+			}
+		}
+
+		c.JSON(http.StatusOK, suggestions)
+	}
+}
+
+func insertPurchaseOrder(ctx context.Context, purchaseOrder *models.PurchaseOrder) (*mongo.InsertOneResult, error) {
+	var err error
+
+	purchaseOrder.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return nil, err
+	}
+
+	purchaseOrder.Updated_at = purchaseOrder.Created_at
+	purchaseOrder.ID = primitive.NewObjectID()
+	purchaseOrder.Purchase_order_id = purchaseOrder.ID.Hex()
+
+	return purchaseOrderCollection.InsertOne(ctx, purchaseOrder)
+}
+
+func checkPurchaseOrderIngredients(ctx context.Context, lines []models.PurchaseOrderLine) error {
+	recipeLines := []models.RecipeLine{}
+
+	for _, line := range lines {
+		recipeLines = append(recipeLines, models.RecipeLine{Ingredient_id: line.Ingredient_id, Amount: line.Quantity})
+	}
+
+	return checkRecipeIngredients(ctx, recipeLines)
+}
+
+// ingredient amounts used by the order items sold since the given time, keyed by ingredient_id
+func ingredientConsumption(ctx context.Context, since time.Time) (map[string]float64, error) {
+	matchStage := bson.D{
+		{Key: "$match", Value: bson.D{
+			{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}},
+			{Key: "voided_at", Value: nil},
+			{Key: "food_id", Value: bson.D{{Key: "$ne", Value: nil}}},
+		}},
+	}
+
+	groupStage := bson.D{
+		{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "food_id", Value: "$food_id"},
+				{Key: "quantity", Value: "$quantity"},
+			}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}},
+	}
+
+	result, err := orderItemCollection.Aggregate(ctx, mongo.Pipeline{matchStage, groupStage})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var soldItems []struct {
+		ID struct {
+			Food_id  string `bson:"food_id"`
+			Quantity string `bson:"quantity"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}
+
+	if err := result.All(ctx, &soldItems); err != nil {
+		return nil, err
+	}
+
+	consumption := map[string]float64{}
+
+	for _, soldItem := range soldItems {
+		size := soldItem.ID.Quantity
+
+		recipe, err := recipeForFood(ctx, soldItem.ID.Food_id, &size)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if recipe == nil {
+			continue
+		}
+
+		for _, line := range recipe.Lines {
+			consumption[*line.Ingredient_id] += *line.Amount * float64(soldItem.Count)
+		}
+	}
+
+	return consumption, nil
+}
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var recipeCollection *mongo.Collection = database.OpenCollection(database.Client, "recipe")
+
+func GetRecipes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := bson.M{}
+
+		if foodId := c.Query("food_id"); foodId != "" {
+			filter["food_id"] = foodId
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := recipeCollection.Find(ctx, filter)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving recipes from database"})
+			return
+		}
+
+		var allRecipes []bson.M
+
+		if err := result.All(ctx, &allRecipes); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allRecipes)
+	}
+}
+
+func GetRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		recipeId := c.Param("recipe_id")
+		var recipe models.Recipe
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := recipeCollection.FindOne(ctx, bson.M{"recipe_id": recipeId}).Decode(&recipe)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "recipe not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, recipe)
+	}
+}
+
+func CreateRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var recipe models.Recipe
+		var food models.Food
+
+		if err := c.BindJSON(&recipe); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(recipe)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		err := foodCollection.FindOne(ctx, bson.M{"food_id": recipe.Food_id}).Decode(&food)
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "food not found"})
+			return
+		}
+
+		count, err := recipeCollection.CountDocuments(ctx, bson.M{"food_id": recipe.Food_id, "size": recipe.Size})
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while checking existing recipes"})
+			return
+		}
+
+		if count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "this food already has a recipe for this size"})
+			return
+		}
+
+		if err := checkRecipeIngredients(ctx, recipe.Lines); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		recipe.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+			return
+		}
+
+		recipe.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		recipe.ID = primitive.NewObjectID()
+		recipe.Recipe_id = recipe.ID.Hex()
+
+		result, insertErr := recipeCollection.InsertOne(ctx, recipe)
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "recipe is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateRecipe() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var recipe models.Recipe
+
+		if err := c.BindJSON(&recipe); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		recipeId := c.Param("recipe_id")
+		var updateObj primitive.D
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		if recipe.Lines != nil {
+			if err := validate.Var(recipe.Lines, "min=1,dive"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := checkRecipeIngredients(ctx, recipe.Lines); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "lines", Value: recipe.Lines})
+		}
+
+		var err error
+		recipe.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: recipe.Updated_at})
+
+		upsert := true
+		filter := bson.M{"recipe_id": recipeId}
+		opt := options.UpdateOptions{
+			Upsert: &upsert,
+		}
+
+		result, err := recipeCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: updateObj},
+			},
+			&opt,
+		)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "recipe updated failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func checkRecipeIngredients(ctx context.Context, lines []models.RecipeLine) error {
+	for _, line := range lines {
+		count, err := ingredientCollection.CountDocuments(ctx, bson.M{"ingredient_id": line.Ingredient_id})
+
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return errors.New("ingredient " + *line.Ingredient_id + " not found")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"go-restaurant-management/payments"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var refundCollection *mongo.Collection = database.OpenCollection(database.Client, "refund")
+
+func GetInvoiceRefunds() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invoiceId := c.Param("invoice_id")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := refundCollection.Find(ctx, bson.M{"invoice_id": invoiceId})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving refunds from database"})
+			return
+		}
+
+		var allRefunds []bson.M
+
+		if err := result.All(ctx, &allRefunds); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allRefunds)
+	}
+}
+
+func CreateRefund() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invoiceId := c.Param("invoice_id")
+		var refund models.Refund
+
+		if err := c.BindJSON(&refund); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(refund)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		managerId, err := approvingManager(ctx, refund.Approval)
+
+		if err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		refund.Invoice_id = invoiceId
+		refund.User_id = c.GetString("uid")
+		refund.Approved_by = managerId
+		refund.Idempotency_key = c.GetHeader("Idempotency-Key")
+
+		invoice, err := recordRefund(ctx, &refund)
+
+		// the refund stays PENDING; retrying with its idempotency key finishes it
+		if errors.Is(err, payments.ErrTimeout) {
+			log.Println(err)
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": err.Error(), "refund": refund})
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"refund":          refund,
+			"amount_refunded": invoice.Amount_refunded,
+			"payment_status":  invoice.Payment_status,
+		})
+	}
+}
+
+// gives back part or all of a recorded payment, tip included, and adds the
+// refund to the invoice's history; the invoice is REFUNDED once every payment
+// has been given back
+func recordRefund(ctx context.Context, refund *models.Refund) (*models.Invoice, error) {
+	var invoice models.Invoice
+
+	if err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": refund.Invoice_id}).Decode(&invoice); err != nil {
+		return nil, errors.New("invoice not found")
+	}
+
+	// a retried request gets the refund recorded the first time, and finishes
+	// a card refund the provider did not answer
+	if refund.Idempotency_key != "" {
+		var existing models.Refund
+
+		err := refundCollection.FindOne(ctx, bson.M{"invoice_id": refund.Invoice_id, "idempotency_key": refund.Idempotency_key}).Decode(&existing)
+
+		if err == nil {
+			*refund = existing
+
+			switch refund.Status {
+			case "PENDING":
+				return completeCardRefund(ctx, refund)
+			case "FAILED":
+				return nil, errors.New("refund failed: " + refund.Error)
+			}
+
+			return &invoice, nil
+		}
+
+		if err != mongo.ErrNoDocuments {
+			return nil, err
+		}
+	}
+
+	var payment models.Payment
+
+	err := paymentCollection.FindOne(ctx, bson.M{"payment_id": refund.Payment_id, "invoice_id": refund.Invoice_id}).Decode(&payment)
+
+	if err != nil {
+		return nil, errors.New("payment not found")
+	}
+
+	// payments recorded before card providers existed have no status
+	if payment.Status != "" && payment.Status != "COMPLETED" {
+		return nil, errors.New("only completed payments can be refunded")
+	}
+
+	var tip int64
+
+	if payment.Tip != nil {
+		tip = toCents(*payment.Tip)
+	}
+
+	amount := toCents(*refund.Amount)
+	refunded := toCents(payment.Amount_refunded)
+
+	if amount > toCents(*payment.Amount)+tip-refunded {
+		return nil, errors.New("refund exceeds what is left of the payment")
+	}
+
+	now, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return nil, err
+	}
+
+	refund.Amount = floatPointer(fromCents(amount))
+	refund.Method = *payment.Method
+	refund.Status = "COMPLETED"
+	refund.Error = ""
+	refund.Provider_reference = ""
+	refund.Created_at = now
+	refund.ID = primitive.NewObjectID()
+	refund.Refund_id = refund.ID.Hex()
+
+	if refund.Idempotency_key == "" {
+		refund.Idempotency_key = refund.Refund_id
+	}
+
+	// the card provider is only called once the refund is recorded, so a
+	// refunded card always has a refund to show for it
+	if refund.Method == "CARD" {
+		refund.Status = "PENDING"
+	}
+
+	// cash is paid back out of the drawer of the till the user opened
+	if refund.Method == "CASH" {
+		session, err := openTillSession(ctx, refund.User_id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		refund.Till_session_id = session.Till_session_id
+	}
+
+	// the filter on amount_refunded keeps two refunds from giving back more than was paid
+	filter := bson.M{"payment_id": payment.Payment_id, "amount_refunded": payment.Amount_refunded}
+
+	if payment.Amount_refunded == 0 {
+		filter["amount_refunded"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	var updated *models.Invoice
+
+	// the payment, the refund and the invoice change together; a card refund
+	// only holds its amount on the payment until the provider answers
+	err = database.Transaction(ctx, func(ctx context.Context) error {
+		result, err := paymentCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{{Key: "$set", Value: bson.D{{Key: "amount_refunded", Value: fromCents(refunded + amount)}}}},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if result.MatchedCount == 0 {
+			return errors.New("payment was changed by another refund, please retry")
+		}
+
+		if _, err := refundCollection.InsertOne(ctx, refund); err != nil {
+			return err
+		}
+
+		if refund.Status == "PENDING" {
+			return nil
+		}
+
+		updated, err = creditRefund(ctx, refund)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.Status == "PENDING" {
+		return completeCardRefund(ctx, refund)
+	}
+
+	return updated, nil
+}
+
+// refunds a recorded PENDING refund through the card provider. A refund the
+// provider turns down is FAILED and its amount goes back to the payment; one it
+// does not answer stays PENDING, for a retry with the same idempotency key.
+func completeCardRefund(ctx context.Context, refund *models.Refund) (*models.Invoice, error) {
+	var payment models.Payment
+
+	if err := paymentCollection.FindOne(ctx, bson.M{"payment_id": refund.Payment_id}).Decode(&payment); err != nil {
+		return nil, errors.New("payment not found")
+	}
+
+	amount := toCents(*refund.Amount)
+	refundErr := refundCardPayment(ctx, &payment, refund, amount)
+
+	if errors.Is(refundErr, payments.ErrTimeout) {
+		return nil, refundErr
+	}
+
+	var updated *models.Invoice
+
+	// the status filter lets only one of two retries finish the refund
+	err := database.Transaction(ctx, func(ctx context.Context) error {
+		status := "COMPLETED"
+		message := ""
+
+		if refundErr != nil {
+			status = "FAILED"
+			message = refundErr.Error()
+		}
+
+		result, err := refundCollection.UpdateOne(
+			ctx,
+			bson.M{"refund_id": refund.Refund_id, "status": "PENDING"},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "status", Value: status},
+				{Key: "error", Value: message},
+				{Key: "provider_reference", Value: refund.Provider_reference},
+			}}},
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if result.MatchedCount == 0 {
+			return nil
+		}
+
+		refund.Status = status
+		refund.Error = message
+
+		if refundErr == nil {
+			updated, err = creditRefund(ctx, refund)
+			return err
+		}
+
+		var current models.Payment
+
+		if err := paymentCollection.FindOne(ctx, bson.M{"payment_id": refund.Payment_id}).Decode(&current); err != nil {
+			return err
+		}
+
+		_, err = paymentCollection.UpdateOne(
+			ctx,
+			bson.M{"payment_id": refund.Payment_id},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "amount_refunded", Value: fromCents(toCents(current.Amount_refunded) - amount)}}}},
+		)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if refundErr != nil {
+		return nil, refundErr
+	}
+
+	// another retry finished the refund first
+	if updated == nil {
+		var invoice models.Invoice
+
+		if err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": refund.Invoice_id}).Decode(&invoice); err != nil {
+			return nil, err
+		}
+
+		updated = &invoice
+	}
+
+	return updated, nil
+}
+
+// adds a completed refund to its invoice, which is REFUNDED once every
+// payment has been given back
+func creditRefund(ctx context.Context, refund *models.Refund) (*models.Invoice, error) {
+	now, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var updated models.Invoice
+
+	// refunds of other payments of the invoice may be recorded at the same time
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err = invoiceCollection.FindOneAndUpdate(
+		ctx,
+		bson.M{"invoice_id": refund.Invoice_id},
+		bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "amount_refunded", Value: *refund.Amount}}},
+			{Key: "$set", Value: bson.D{{Key: "updated_at", Value: now}}},
+			{Key: "$push", Value: bson.D{
+				{Key: "history", Value: models.InvoiceEvent{
+					Type:         "REFUND",
+					Reference_id: refund.Refund_id,
+					Amount:       *refund.Amount,
+					Reason:       *refund.Reason,
+					User_id:      refund.User_id,
+					Approved_by:  refund.Approved_by,
+					Created_at:   now,
+				}},
+			}},
+		},
+		opts,
+	).Decode(&updated)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var invoiceTip int64
+
+	if updated.Tip != nil {
+		invoiceTip = toCents(*updated.Tip)
+	}
+
+	if toCents(updated.Amount_refunded) < toCents(updated.Amount_paid)+invoiceTip {
+		return &updated, nil
+	}
+
+	status := "REFUNDED"
+	updated.Payment_status = &status
+
+	_, err = invoiceCollection.UpdateOne(
+		ctx,
+		bson.M{"invoice_id": refund.Invoice_id},
+		bson.D{{Key: "$set", Value: bson.D{{Key: "payment_status", Value: status}}}},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func refundCardPayment(ctx context.Context, payment *models.Payment, refund *models.Refund, amount int64) error {
+	provider, err := payments.Provider()
+
+	if err != nil {
+		return err
+	}
+
+	result, err := provider.Refund(ctx, payment.Provider_reference, amount, refund.Idempotency_key)
+
+	if err != nil {
+		return err
+	}
+
+	refund.Provider_reference = result.Reference
+
+	return nil
+}
@@ -0,0 +1,754 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type FoodCost struct {
+	Food_id           string  `json:"food_id"`
+	Name              string  `json:"name"`
+	Size              string  `json:"size"`
+	Price             float64 `json:"price"`
+	Cost              float64 `json:"cost"`
+	Gross_margin      float64 `json:"gross_margin"`
+	Margin_percentage float64 `json:"margin_percentage"`
+}
+
+type MenuEngineeringItem struct {
+	Food_id                     string  `json:"food_id"`
+	Name                        string  `json:"name"`
+	Items_sold                  int     `json:"items_sold"`
+	Revenue                     float64 `json:"revenue"`
+	Food_cost                   float64 `json:"food_cost"`
+	Contribution_margin         float64 `json:"contribution_margin"`
+	Average_contribution_margin float64 `json:"average_contribution_margin"`
+	Margin_percentage           float64 `json:"margin_percentage"`
+	Popularity_percentage       float64 `json:"popularity_percentage"`
+	Margin_rank                 int     `json:"margin_rank"`
+	Popularity_rank             int     `json:"popularity_rank"`
+	Classification              string  `json:"classification"` // STAR, PLOWHORSE, PUZZLE or DOG
+}
+
+func GetFoodCostReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		costs, err := ingredientCosts(ctx)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving ingredient costs"})
+			return
+		}
+
+		result, err := recipeCollection.Find(ctx, bson.M{})
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving recipes from database"})
+			return
+		}
+
+		var recipes []models.Recipe
+
+		if err := result.All(ctx, &recipes); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		foodCosts := []FoodCost{}
+
+		for _, recipe := range recipes {
+			var food models.Food
+
+			if err := foodCollection.FindOne(ctx, bson.M{"food_id": recipe.Food_id}).Decode(&food); err != nil {
+				continue
+			}
+
+			foodCost := FoodCost{
+				Food_id: food.Food_id,
+				Name:    *food.Name,
+				Price:   *food.Price,
+				Cost:    recipeCost(recipe, costs),
+			}
+
+			if recipe.Size != nil {
+				foodCost.Size = *recipe.Size
+			}
+
+			foodCost.Gross_margin = toFixed(foodCost.Price-foodCost.Cost, 2)
+			foodCost.Margin_percentage = percentage(foodCost.Gross_margin, foodCost.Price)
+
+			foodCosts = append(foodCosts, foodCost)
+		}
+
+		sort.Slice(foodCosts, func(i, j int) bool {
+			return foodCosts[i].Margin_percentage > foodCosts[j].Margin_percentage
+		})
+
+		c.JSON(http.StatusOK, foodCosts)
+	}
+}
+
+func GetMenuEngineeringReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startDate, endDate, err := dateRange(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		costs, err := ingredientCosts(ctx)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving ingredient costs"})
+			return
+		}
+
+		// combo components are sold at no price of their own, so they are left out
+		matchStage := bson.D{
+			{Key: "$match", Value: bson.D{
+				{Key: "created_at", Value: bson.D{{Key: "$gte", Value: startDate}, {Key: "$lt", Value: endDate}}},
+				{Key: "voided_at", Value: nil},
+				{Key: "parent_order_item_id", Value: nil},
+				{Key: "food_id", Value: bson.D{{Key: "$ne", Value: nil}}},
+			}},
+		}
+
+		groupStage := bson.D{
+			{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{
+					{Key: "food_id", Value: "$food_id"},
+					{Key: "quantity", Value: "$quantity"},
+				}},
+				{Key: "items_sold", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "revenue", Value: bson.D{{Key: "$sum", Value: "$unit_price"}}},
+			}},
+		}
+
+		result, err := orderItemCollection.Aggregate(ctx, mongo.Pipeline{matchStage, groupStage})
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating order items"})
+			return
+		}
+
+		var soldItems []struct {
+			ID struct {
+				Food_id  string `bson:"food_id"`
+				Quantity string `bson:"quantity"`
+			} `bson:"_id"`
+			Items_sold int     `bson:"items_sold"`
+			Revenue    float64 `bson:"revenue"`
+		}
+
+		if err := result.All(ctx, &soldItems); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		byFood := map[string]*MenuEngineeringItem{}
+		items := []*MenuEngineeringItem{}
+		totalSold := 0
+		totalMargin := 0.0
+
+		for _, soldItem := range soldItems {
+			item, ok := byFood[soldItem.ID.Food_id]
+
+			if !ok {
+				var food models.Food
+
+				if err := foodCollection.FindOne(ctx, bson.M{"food_id": soldItem.ID.Food_id}).Decode(&food); err != nil {
+					continue
+				}
+
+				item = &MenuEngineeringItem{Food_id: food.Food_id, Name: *food.Name}
+				byFood[food.Food_id] = item
+				items = append(items, item)
+			}
+
+			size := soldItem.ID.Quantity
+
+			recipe, err := recipeForFood(ctx, soldItem.ID.Food_id, &size)
+
+			if err != nil {
+				log.Println(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving recipes from database"})
+				return
+			}
+
+			if recipe != nil {
+				item.Food_cost += recipeCost(*recipe, costs) * float64(soldItem.Items_sold)
+			}
+
+			item.Items_sold += soldItem.Items_sold
+			item.Revenue += soldItem.Revenue
+			totalSold += soldItem.Items_sold
+		}
+
+		for _, item := range items {
+			item.Revenue = toFixed(item.Revenue, 2)
+			item.Food_cost = toFixed(item.Food_cost, 2)
+			item.Contribution_margin = toFixed(item.Revenue-item.Food_cost, 2)
+			item.Average_contribution_margin = toFixed(item.Contribution_margin/float64(item.Items_sold), 2)
+			item.Margin_percentage = percentage(item.Contribution_margin, item.Revenue)
+			item.Popularity_percentage = percentage(float64(item.Items_sold), float64(totalSold))
+			totalMargin += item.Contribution_margin
+		}
+
+		// an item is popular when it sells at least 70% of an equal share of the mix,
+		// and profitable when its margin per item beats the weighted average
+		popularityThreshold := 0.0
+		marginThreshold := 0.0
+
+		if len(items) > 0 && totalSold > 0 {
+			popularityThreshold = 100.0 / float64(len(items)) * 0.7
+			marginThreshold = totalMargin / float64(totalSold)
+		}
+
+		for _, item := range items {
+			popular := item.Popularity_percentage >= popularityThreshold
+			profitable := item.Average_contribution_margin >= marginThreshold
+
+			switch {
+			case popular && profitable:
+				item.Classification = "STAR"
+			case popular:
+				item.Classification = "PLOWHORSE"
+			case profitable:
+				item.Classification = "PUZZLE"
+			default:
+				item.Classification = "DOG"
+			}
+		}
+
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Items_sold > items[j].Items_sold })
+
+		for i, item := range items {
+			item.Popularity_rank = i + 1
+		}
+
+		sort.SliceStable(items, func(i, j int) bool { return items[i].Contribution_margin > items[j].Contribution_margin })
+
+		for i, item := range items {
+			item.Margin_rank = i + 1
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"start_date":           startDate,
+			"end_date":             endDate.AddDate(0, 0, -1),
+			"total_items_sold":     totalSold,
+			"popularity_threshold": toFixed(popularityThreshold, 2),
+			"margin_threshold":     toFixed(marginThreshold, 2),
+			"items":                items,
+		})
+	}
+}
+
+func GetWasteReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startDate, endDate, err := dateRange(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		matchStage := bson.D{
+			{Key: "$match", Value: bson.D{
+				{Key: "created_at", Value: bson.D{{Key: "$gte", Value: startDate}, {Key: "$lt", Value: endDate}}},
+			}},
+		}
+
+		byReason := mongo.Pipeline{
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$reason"},
+				{Key: "events", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "cost", Value: bson.D{{Key: "$sum", Value: "$cost"}}},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "cost", Value: -1}}}},
+		}
+
+		byIngredient := mongo.Pipeline{
+			bson.D{{Key: "$unwind", Value: "$lines"}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$lines.ingredient_id"},
+				{Key: "quantity", Value: bson.D{{Key: "$sum", Value: "$lines.quantity"}}},
+				{Key: "cost", Value: bson.D{{Key: "$sum", Value: "$lines.cost"}}},
+			}}},
+			bson.D{{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: "ingredient"},
+				{Key: "localField", Value: "_id"},
+				{Key: "foreignField", Value: "ingredient_id"},
+				{Key: "as", Value: "ingredient"},
+			}}},
+			bson.D{{Key: "$unwind", Value: bson.D{
+				{Key: "path", Value: "$ingredient"},
+				{Key: "preserveNullAndEmptyArrays", Value: true},
+			}}},
+			bson.D{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "ingredient_id", Value: "$_id"},
+				{Key: "name", Value: "$ingredient.name"},
+				{Key: "unit", Value: "$ingredient.unit"},
+				{Key: "quantity", Value: 1},
+				{Key: "cost", Value: 1},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "cost", Value: -1}}}},
+		}
+
+		byDay := mongo.Pipeline{
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+					{Key: "format", Value: "%Y-%m-%d"},
+					{Key: "date", Value: "$created_at"},
+				}}}},
+				{Key: "events", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "cost", Value: bson.D{{Key: "$sum", Value: "$cost"}}},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		}
+
+		total := mongo.Pipeline{
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: nil},
+				{Key: "events", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "cost", Value: bson.D{{Key: "$sum", Value: "$cost"}}},
+			}}},
+		}
+
+		facetStage := bson.D{
+			{Key: "$facet", Value: bson.D{
+				{Key: "by_reason", Value: byReason},
+				{Key: "by_ingredient", Value: byIngredient},
+				{Key: "by_day", Value: byDay},
+				{Key: "total", Value: total},
+			}},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := wasteCollection.Aggregate(ctx, mongo.Pipeline{matchStage, facetStage})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating waste"})
+			return
+		}
+
+		var report []bson.M
+
+		if err := result.All(ctx, &report); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, report[0])
+	}
+}
+
+func GetTipsReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startDate, endDate, err := dateRange(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		matchStage := bson.D{
+			{Key: "$match", Value: bson.D{
+				{Key: "created_at", Value: bson.D{{Key: "$gte", Value: startDate}, {Key: "$lt", Value: endDate}}},
+			}},
+		}
+
+		// tips belong to the server who owned the order
+		groupStage := bson.D{
+			{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: "$server_id"},
+				{Key: "invoices", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "tips", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$tip", 0}}}}}},
+				{Key: "service_charge", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$service_charge", 0}}}}}},
+				{Key: "sales", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$total", 0}}}}}},
+			}},
+		}
+
+		lookupUserStage := bson.D{
+			{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: "user"},
+				{Key: "localField", Value: "_id"},
+				{Key: "foreignField", Value: "user_id"},
+				{Key: "as", Value: "user"},
+			}},
+		}
+
+		unwindUserStage := bson.D{
+			{Key: "$unwind", Value: bson.D{
+				{Key: "path", Value: "$user"},
+				{Key: "preserveNullAndEmptyArrays", Value: true},
+			}},
+		}
+
+		projectStage := bson.D{
+			{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "server_id", Value: "$_id"},
+				{Key: "first_name", Value: "$user.first_name"},
+				{Key: "last_name", Value: "$user.last_name"},
+				{Key: "invoices", Value: 1},
+				{Key: "tips", Value: 1},
+				{Key: "service_charge", Value: 1},
+				{Key: "sales", Value: 1},
+			}},
+		}
+
+		sortStage := bson.D{
+			{Key: "$sort", Value: bson.D{{Key: "tips", Value: -1}}},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := invoiceCollection.Aggregate(ctx, mongo.Pipeline{
+			matchStage,
+			groupStage,
+			lookupUserStage,
+			unwindUserStage,
+			projectStage,
+			sortStage,
+		})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating tips"})
+			return
+		}
+
+		var tips []bson.M
+
+		if err := result.All(ctx, &tips); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, tips)
+	}
+}
+
+func GetDiscountsReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startDate, endDate, err := dateRange(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		matchStage := bson.D{
+			{Key: "$match", Value: bson.D{
+				{Key: "created_at", Value: bson.D{{Key: "$gte", Value: startDate}, {Key: "$lt", Value: endDate}}},
+			}},
+		}
+
+		unwindDiscountsStage := bson.D{
+			{Key: "$unwind", Value: "$discounts"},
+		}
+
+		lookupUserStage := bson.D{
+			{Key: "$lookup", Value: bson.D{
+				{Key: "from", Value: "user"},
+				{Key: "localField", Value: "discounts.applied_by"},
+				{Key: "foreignField", Value: "user_id"},
+				{Key: "as", Value: "user"},
+			}},
+		}
+
+		unwindUserStage := bson.D{
+			{Key: "$unwind", Value: bson.D{
+				{Key: "path", Value: "$user"},
+				{Key: "preserveNullAndEmptyArrays", Value: true},
+			}},
+		}
+
+		projectStage := bson.D{
+			{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "invoice_id", Value: 1},
+				{Key: "order_id", Value: 1},
+				{Key: "created_at", Value: 1},
+				{Key: "promotion_id", Value: "$discounts.promotion_id"},
+				{Key: "name", Value: "$discounts.name"},
+				{Key: "code", Value: "$discounts.code"},
+				{Key: "scope", Value: "$discounts.scope"},
+				{Key: "amount", Value: "$discounts.amount"},
+				{Key: "reason", Value: "$discounts.reason"},
+				{Key: "applied_by", Value: "$discounts.applied_by"},
+				{Key: "applied_by_name", Value: bson.D{{Key: "$concat", Value: bson.A{
+					bson.D{{Key: "$ifNull", Value: bson.A{"$user.first_name", ""}}},
+					" ",
+					bson.D{{Key: "$ifNull", Value: bson.A{"$user.last_name", ""}}},
+				}}}},
+			}},
+		}
+
+		byUser := mongo.Pipeline{
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{
+					{Key: "applied_by", Value: "$applied_by"},
+					{Key: "applied_by_name", Value: "$applied_by_name"},
+				}},
+				{Key: "discounts", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "amount", Value: -1}}}},
+		}
+
+		facetStage := bson.D{
+			{Key: "$facet", Value: bson.D{
+				{Key: "by_user", Value: byUser},
+				{Key: "discounts", Value: mongo.Pipeline{
+					bson.D{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
+				}},
+			}},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := invoiceCollection.Aggregate(ctx, mongo.Pipeline{
+			matchStage,
+			unwindDiscountsStage,
+			lookupUserStage,
+			unwindUserStage,
+			projectStage,
+			facetStage,
+		})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating discounts"})
+			return
+		}
+
+		var report []bson.M
+
+		if err := result.All(ctx, &report); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, report[0])
+	}
+}
+
+// refunds and voids per day, with the reasons and approving managers
+func GetRefundsReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startDate, endDate, err := dateRange(c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// card refunds still waiting for, or turned down by, the provider gave nothing back
+		refundsByDay := mongo.Pipeline{
+			bson.D{{Key: "$match", Value: bson.D{
+				{Key: "created_at", Value: bson.D{{Key: "$gte", Value: startDate}, {Key: "$lt", Value: endDate}}},
+				{Key: "status", Value: bson.D{{Key: "$nin", Value: bson.A{"PENDING", "FAILED"}}}},
+			}}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$dateToString", Value: bson.D{{Key: "format", Value: "%Y-%m-%d"}, {Key: "date", Value: "$created_at"}}}}},
+				{Key: "refunds", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$amount"}}},
+				{Key: "details", Value: bson.D{{Key: "$push", Value: bson.D{
+					{Key: "refund_id", Value: "$refund_id"},
+					{Key: "invoice_id", Value: "$invoice_id"},
+					{Key: "method", Value: "$method"},
+					{Key: "amount", Value: "$amount"},
+					{Key: "reason", Value: "$reason"},
+					{Key: "user_id", Value: "$user_id"},
+					{Key: "approved_by", Value: "$approved_by"},
+				}}}},
+			}}},
+			bson.D{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "day", Value: "$_id"},
+				{Key: "refunds", Value: 1},
+				{Key: "amount", Value: 1},
+				{Key: "details", Value: 1},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "day", Value: 1}}}},
+		}
+
+		// combo children are voided with their bundle line, so only top-level lines count
+		voidsByDay := mongo.Pipeline{
+			bson.D{{Key: "$match", Value: bson.D{
+				{Key: "voided_at", Value: bson.D{{Key: "$gte", Value: startDate}, {Key: "$lt", Value: endDate}}},
+				{Key: "parent_order_item_id", Value: nil},
+			}}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: bson.D{{Key: "$dateToString", Value: bson.D{{Key: "format", Value: "%Y-%m-%d"}, {Key: "date", Value: "$voided_at"}}}}},
+				{Key: "voids", Value: bson.D{{Key: "$sum", Value: 1}}},
+				{Key: "amount", Value: bson.D{{Key: "$sum", Value: "$unit_price"}}},
+				{Key: "details", Value: bson.D{{Key: "$push", Value: bson.D{
+					{Key: "order_item_id", Value: "$order_item_id"},
+					{Key: "order_id", Value: "$order_id"},
+					{Key: "amount", Value: "$unit_price"},
+					{Key: "reason", Value: "$void_reason"},
+					{Key: "user_id", Value: "$voided_by"},
+					{Key: "approved_by", Value: "$void_approved_by"},
+				}}}},
+			}}},
+			bson.D{{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "day", Value: "$_id"},
+				{Key: "voids", Value: 1},
+				{Key: "amount", Value: 1},
+				{Key: "details", Value: 1},
+			}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "day", Value: 1}}}},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		refundResult, err := refundCollection.Aggregate(ctx, refundsByDay)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating refunds"})
+			return
+		}
+
+		refunds := []bson.M{}
+
+		if err := refundResult.All(ctx, &refunds); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		voidResult, err := orderItemCollection.Aggregate(ctx, voidsByDay)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating voids"})
+			return
+		}
+
+		voids := []bson.M{}
+
+		if err := voidResult.All(ctx, &voids); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"refunds": refunds, "voids": voids})
+	}
+}
+
+// unit cost of every ingredient, keyed by ingredient_id
+func ingredientCosts(ctx context.Context) (map[string]float64, error) {
+	result, err := ingredientCollection.Find(ctx, bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var ingredients []models.Ingredient
+
+	if err := result.All(ctx, &ingredients); err != nil {
+		return nil, err
+	}
+
+	costs := map[string]float64{}
+
+	for _, ingredient := range ingredients {
+		if ingredient.Unit_cost != nil {
+			costs[ingredient.Ingredient_id] = *ingredient.Unit_cost
+		}
+	}
+
+	return costs, nil
+}
+
+func recipeCost(recipe models.Recipe, costs map[string]float64) float64 {
+	cost := 0.0
+
+	for _, line := range recipe.Lines {
+		cost += *line.Amount * costs[*line.Ingredient_id]
+	}
+
+	return toFixed(cost, 2)
+}
+
+func percentage(part float64, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+
+	return toFixed(part/whole*100, 2)
+}
+
+// reads start_date and end_date (YYYY-MM-DD, end date inclusive) as UTC days
+func dateRange(c *gin.Context) (time.Time, time.Time, error) {
+	return parseDateRange(c.Query("start_date"), c.Query("end_date"), time.UTC)
+}
+
+// the start of the first day and the end of the last day of the range, each
+// at midnight in location; the last 30 days by default
+func parseDateRange(start string, end string, location *time.Location) (time.Time, time.Time, error) {
+	now := time.Now().In(location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	startDate := today.AddDate(0, 0, -29)
+	endDate := today
+
+	var err error
+
+	if start != "" {
+		startDate, err = time.ParseInLocation("2006-01-02", start, location)
+
+		if err != nil {
+			return startDate, endDate, errors.New("start_date must be formatted as YYYY-MM-DD")
+		}
+	}
+
+	if end != "" {
+		endDate, err = time.ParseInLocation("2006-01-02", end, location)
+
+		if err != nil {
+			return startDate, endDate, errors.New("end_date must be formatted as YYYY-MM-DD")
+		}
+	}
+
+	if endDate.Before(startDate) {
+		return startDate, endDate, errors.New("end_date must not be before start_date")
+	}
+
+	return startDate, endDate.AddDate(0, 0, 1), nil
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateRange(t *testing.T) {
+	kualaLumpur, err := time.LoadLocation("Asia/Kuala_Lumpur")
+
+	if err != nil {
+		t.Skip("timezone database not available")
+	}
+
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		location  *time.Location
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{
+			name:      "utc days",
+			start:     "2026-03-01",
+			end:       "2026-03-31",
+			location:  time.UTC,
+			wantStart: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "days start at midnight in the timezone",
+			start:     "2026-03-01",
+			end:       "2026-03-01",
+			location:  kualaLumpur,
+			wantStart: time.Date(2026, 2, 28, 16, 0, 0, 0, time.UTC),
+			wantEnd:   time.Date(2026, 3, 1, 16, 0, 0, 0, time.UTC),
+		},
+		{name: "bad start", start: "01/03/2026", location: time.UTC, wantErr: true},
+		{name: "end before start", start: "2026-03-02", end: "2026-03-01", location: time.UTC, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			startDate, endDate, err := parseDateRange(test.start, test.end, test.location)
+
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want error %v", err, test.wantErr)
+			}
+
+			if test.wantErr {
+				return
+			}
+
+			if !startDate.Equal(test.wantStart) || !endDate.Equal(test.wantEnd) {
+				t.Errorf("range %v to %v, want %v to %v", startDate.UTC(), endDate.UTC(), test.wantStart, test.wantEnd)
+			}
+		})
+	}
+}
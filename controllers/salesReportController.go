@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// $dateToString formats of the buckets the sales report can chart
+var salesBuckets = map[string]string{
+	"hour":  "%Y-%m-%dT%H:00",
+	"day":   "%Y-%m-%d",
+	"week":  "%G-W%V",
+	"month": "%Y-%m",
+}
+
+// revenue, orders, items sold and average ticket per hour, day, week or month.
+// Filters: menu_id, category, food_id, table_id and server_id; with a menu,
+// category or food filter, combo bundles are left out as they have no food.
+func GetSalesReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timezone := c.DefaultQuery("timezone", "UTC")
+		location, err := time.LoadLocation(timezone)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown timezone " + timezone})
+			return
+		}
+
+		// the dates are days in the restaurant's timezone, like the buckets
+		startDate, endDate, err := parseDateRange(c.Query("start_date"), c.Query("end_date"), location)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		bucket := c.DefaultQuery("bucket", "day")
+		format, ok := salesBuckets[bucket]
+
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be hour, day, week or month"})
+			return
+		}
+
+		pipeline := salesPipeline(c, startDate, endDate, c.Query("food_id"))
+
+		// buckets follow the restaurant's timezone, so a day runs from its own midnight
+		addBucketStage := bson.D{
+			{Key: "$addFields", Value: bson.D{
+				{Key: "bucket", Value: bson.D{{Key: "$dateToString", Value: bson.D{
+					{Key: "format", Value: format},
+					{Key: "date", Value: "$created_at"},
+					{Key: "timezone", Value: timezone},
+				}}}},
+			}},
+		}
+
+		salesGroup := func(id interface{}) bson.D {
+			return bson.D{
+				{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: id},
+					{Key: "revenue", Value: bson.D{{Key: "$sum", Value: "$unit_price"}}},
+					{Key: "orders", Value: bson.D{{Key: "$addToSet", Value: "$order_id"}}},
+					// a combo bundle is cooked as its child items, which are counted instead
+					{Key: "items_sold", Value: bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{
+						bson.D{{Key: "$gt", Value: bson.A{"$food_id", nil}}}, 1, 0,
+					}}}}}},
+				}},
+			}
+		}
+
+		salesProject := bson.D{
+			{Key: "$project", Value: bson.D{
+				{Key: "_id", Value: 0},
+				{Key: "bucket", Value: "$_id"},
+				{Key: "revenue", Value: bson.D{{Key: "$round", Value: bson.A{"$revenue", 2}}}},
+				{Key: "orders", Value: bson.D{{Key: "$size", Value: "$orders"}}},
+				{Key: "items_sold", Value: 1},
+				{Key: "average_ticket", Value: bson.D{{Key: "$round", Value: bson.A{
+					bson.D{{Key: "$divide", Value: bson.A{"$revenue", bson.D{{Key: "$max", Value: bson.A{bson.D{{Key: "$size", Value: "$orders"}}, 1}}}}}},
+					2,
+				}}}},
+			}},
+		}
+
+		facetStage := bson.D{
+			{Key: "$facet", Value: bson.D{
+				{Key: "buckets", Value: mongo.Pipeline{
+					salesGroup("$bucket"),
+					salesProject,
+					bson.D{{Key: "$sort", Value: bson.D{{Key: "bucket", Value: 1}}}},
+				}},
+				{Key: "totals", Value: mongo.Pipeline{
+					salesGroup(nil),
+					salesProject,
+				}},
+			}},
+		}
+
+		pipeline = append(pipeline, addBucketStage, facetStage)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := orderItemCollection.Aggregate(ctx, pipeline)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while aggregating sales"})
+			return
+		}
+
+		var report []bson.M
+
+		if err := result.All(ctx, &report); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		totals := bson.M{"revenue": 0, "orders": 0, "items_sold": 0, "average_ticket": 0}
+
+		if items, ok := report[0]["totals"].(bson.A); ok && len(items) > 0 {
+			if total, ok := items[0].(bson.M); ok {
+				delete(total, "bucket")
+				totals = total
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"bucket":     bucket,
+			"timezone":   timezone,
+			"start_date": startDate.Format("2006-01-02"),
+			"end_date":   endDate.AddDate(0, 0, -1).Format("2006-01-02"),
+			"buckets":    report[0]["buckets"],
+			"totals":     totals,
+		})
+	}
+}
+
+// order items sold in the date range, joined to their order, food and menu and
+// narrowed down by the request's filters; foodId, when set, keeps that food only
+func salesPipeline(c *gin.Context, startDate time.Time, endDate time.Time, foodId string) mongo.Pipeline {
+	itemFilter := bson.D{
+		{Key: "created_at", Value: bson.D{{Key: "$gte", Value: startDate}, {Key: "$lt", Value: endDate}}},
+		// voided items are not charged
+		{Key: "voided_at", Value: nil},
+	}
+
+	if foodId != "" {
+		itemFilter = append(itemFilter, bson.E{Key: "food_id", Value: foodId})
+	}
+
+	matchStage := bson.D{{Key: "$match", Value: itemFilter}}
+
+	lookupOrderStage := bson.D{
+		{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "order"},
+			{Key: "localField", Value: "order_id"},
+			{Key: "foreignField", Value: "order_id"},
+			{Key: "as", Value: "order"},
+		}},
+	}
+
+	unwindOrderStage := bson.D{
+		{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$order"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}},
+	}
+
+	lookupFoodStage := bson.D{
+		{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "food"},
+			{Key: "localField", Value: "food_id"},
+			{Key: "foreignField", Value: "food_id"},
+			{Key: "as", Value: "food"},
+		}},
+	}
+
+	unwindFoodStage := bson.D{
+		{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$food"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}},
+	}
+
+	lookupMenuStage := bson.D{
+		{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "menu"},
+			{Key: "localField", Value: "food.menu_id"},
+			{Key: "foreignField", Value: "menu_id"},
+			{Key: "as", Value: "menu"},
+		}},
+	}
+
+	unwindMenuStage := bson.D{
+		{Key: "$unwind", Value: bson.D{
+			{Key: "path", Value: "$menu"},
+			{Key: "preserveNullAndEmptyArrays", Value: true},
+		}},
+	}
+
+	pipeline := mongo.Pipeline{
+		matchStage,
+		lookupOrderStage,
+		unwindOrderStage,
+		lookupFoodStage,
+		unwindFoodStage,
+		lookupMenuStage,
+		unwindMenuStage,
+	}
+
+	joinedFilter := bson.D{}
+
+	if menuId := c.Query("menu_id"); menuId != "" {
+		joinedFilter = append(joinedFilter, bson.E{Key: "food.menu_id", Value: menuId})
+	}
+
+	if category := c.Query("category"); category != "" {
+		joinedFilter = append(joinedFilter, bson.E{Key: "menu.category", Value: category})
+	}
+
+	if tableId := c.Query("table_id"); tableId != "" {
+		joinedFilter = append(joinedFilter, bson.E{Key: "order.table_id", Value: tableId})
+	}
+
+	if serverId := c.Query("server_id"); serverId != "" {
+		joinedFilter = append(joinedFilter, bson.E{Key: "order.server_id", Value: serverId})
+	}
+
+	if len(joinedFilter) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: joinedFilter}})
+	}
+
+	return pipeline
+}
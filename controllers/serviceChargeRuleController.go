@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurant-management/database"
+	helper "go-restaurant-management/helpers"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var serviceChargeRuleCollection *mongo.Collection = database.OpenCollection(database.Client, "serviceChargeRule")
+
+func GetServiceChargeRules() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := serviceChargeRuleCollection.Find(ctx, bson.M{})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving service charge rules from database"})
+			return
+		}
+
+		var allServiceChargeRules []bson.M
+
+		if err := result.All(ctx, &allServiceChargeRules); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allServiceChargeRules)
+	}
+}
+
+func GetServiceChargeRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceChargeRuleId := c.Param("service_charge_rule_id")
+		var serviceChargeRule models.ServiceChargeRule
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := serviceChargeRuleCollection.FindOne(ctx, bson.M{"service_charge_rule_id": serviceChargeRuleId}).Decode(&serviceChargeRule)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "service charge rule not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, serviceChargeRule)
+	}
+}
+
+func CreateServiceChargeRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// service charges apply to every invoice, so only managers may change them
+		if err := helper.CheckUserType(c, "MANAGER"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		var serviceChargeRule models.ServiceChargeRule
+
+		if err := c.BindJSON(&serviceChargeRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(serviceChargeRule)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		if serviceChargeRule.Active == nil {
+			active := true
+			serviceChargeRule.Active = &active
+		}
+
+		var err error
+
+		serviceChargeRule.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+			return
+		}
+
+		serviceChargeRule.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		serviceChargeRule.ID = primitive.NewObjectID()
+		serviceChargeRule.Service_charge_rule_id = serviceChargeRule.ID.Hex()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, insertErr := serviceChargeRuleCollection.InsertOne(ctx, serviceChargeRule)
+		defer cancel()
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "service charge rule is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateServiceChargeRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// service charges apply to every invoice, so only managers may change them
+		if err := helper.CheckUserType(c, "MANAGER"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		var serviceChargeRule models.ServiceChargeRule
+
+		if err := c.BindJSON(&serviceChargeRule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		serviceChargeRuleId := c.Param("service_charge_rule_id")
+		var updateObj primitive.D
+
+		if serviceChargeRule.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: serviceChargeRule.Name})
+		}
+
+		if serviceChargeRule.Rate != nil {
+			if err := validate.Var(serviceChargeRule.Rate, "gt=0,lte=100"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "rate", Value: serviceChargeRule.Rate})
+		}
+
+		if serviceChargeRule.Min_guests != nil {
+			updateObj = append(updateObj, bson.E{Key: "min_guests", Value: serviceChargeRule.Min_guests})
+		}
+
+		if serviceChargeRule.Order_types != nil {
+			updateObj = append(updateObj, bson.E{Key: "order_types", Value: serviceChargeRule.Order_types})
+		}
+
+		if serviceChargeRule.Active != nil {
+			updateObj = append(updateObj, bson.E{Key: "active", Value: serviceChargeRule.Active})
+		}
+
+		var err error
+		serviceChargeRule.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: serviceChargeRule.Updated_at})
+
+		filter := bson.M{"service_charge_rule_id": serviceChargeRuleId}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := serviceChargeRuleCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: updateObj},
+			},
+		)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "service charge rule updated failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "service charge rule not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func activeServiceChargeRules(ctx context.Context) ([]models.ServiceChargeRule, error) {
+	result, err := serviceChargeRuleCollection.Find(ctx, bson.M{"active": bson.M{"$ne": false}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var serviceChargeRules []models.ServiceChargeRule
+
+	if err := result.All(ctx, &serviceChargeRules); err != nil {
+		return nil, err
+	}
+
+	return serviceChargeRules, nil
+}
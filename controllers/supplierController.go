@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var supplierCollection *mongo.Collection = database.OpenCollection(database.Client, "supplier")
+
+func GetSuppliers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := supplierCollection.Find(ctx, bson.M{})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving suppliers from database"})
+			return
+		}
+
+		var allSuppliers []bson.M
+
+		if err := result.All(ctx, &allSuppliers); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allSuppliers)
+	}
+}
+
+func GetSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		supplierId := c.Param("supplier_id")
+		var supplier models.Supplier
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := supplierCollection.FindOne(ctx, bson.M{"supplier_id": supplierId}).Decode(&supplier)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "supplier not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, supplier)
+	}
+}
+
+func CreateSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var supplier models.Supplier
+
+		if err := c.BindJSON(&supplier); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(supplier)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		var err error
+
+		supplier.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+			return
+		}
+
+		supplier.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		supplier.ID = primitive.NewObjectID()
+		supplier.Supplier_id = supplier.ID.Hex()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, insertErr := supplierCollection.InsertOne(ctx, supplier)
+		defer cancel()
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "supplier is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateSupplier() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var supplier models.Supplier
+
+		if err := c.BindJSON(&supplier); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		supplierId := c.Param("supplier_id")
+		var updateObj primitive.D
+
+		if supplier.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: supplier.Name})
+		}
+
+		if supplier.Contact_name != nil {
+			updateObj = append(updateObj, bson.E{Key: "contact_name", Value: supplier.Contact_name})
+		}
+
+		if supplier.Email != nil {
+			updateObj = append(updateObj, bson.E{Key: "email", Value: supplier.Email})
+		}
+
+		if supplier.Phone != nil {
+			updateObj = append(updateObj, bson.E{Key: "phone", Value: supplier.Phone})
+		}
+
+		var err error
+		supplier.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: supplier.Updated_at})
+
+		upsert := true
+		filter := bson.M{"supplier_id": supplierId}
+		opt := options.UpdateOptions{
+			Upsert: &upsert,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := supplierCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: updateObj},
+			},
+			&opt,
+		)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "supplier updated failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
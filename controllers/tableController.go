@@ -19,6 +19,11 @@ var tableCollection *mongo.Collection = database.OpenCollection(database.Client,
 
 func GetTables() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if format := exportFormat(c); format != "" {
+			exportList(c, format, tableCollection, bson.M{}, "tables", tableColumns)
+			return
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
 		result, err := tableCollection.Find(ctx, bson.M{})
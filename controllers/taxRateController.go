@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"go-restaurant-management/database"
+	helper "go-restaurant-management/helpers"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var taxRateCollection *mongo.Collection = database.OpenCollection(database.Client, "taxRate")
+
+func GetTaxRates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := taxRateCollection.Find(ctx, bson.M{})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving tax rates from database"})
+			return
+		}
+
+		var allTaxRates []bson.M
+
+		if err := result.All(ctx, &allTaxRates); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allTaxRates)
+	}
+}
+
+func GetTaxRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		taxRateId := c.Param("tax_rate_id")
+		var taxRate models.TaxRate
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := taxRateCollection.FindOne(ctx, bson.M{"tax_rate_id": taxRateId}).Decode(&taxRate)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "tax rate not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, taxRate)
+	}
+}
+
+func CreateTaxRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// tax rates apply to every invoice, so only managers may change them
+		if err := helper.CheckUserType(c, "MANAGER"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		var taxRate models.TaxRate
+
+		if err := c.BindJSON(&taxRate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(taxRate)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		if taxRate.Active == nil {
+			active := true
+			taxRate.Active = &active
+		}
+
+		var err error
+
+		taxRate.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+			return
+		}
+
+		taxRate.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		taxRate.ID = primitive.NewObjectID()
+		taxRate.Tax_rate_id = taxRate.ID.Hex()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, insertErr := taxRateCollection.InsertOne(ctx, taxRate)
+		defer cancel()
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "tax rate is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func UpdateTaxRate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// tax rates apply to every invoice, so only managers may change them
+		if err := helper.CheckUserType(c, "MANAGER"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		var taxRate models.TaxRate
+
+		if err := c.BindJSON(&taxRate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		taxRateId := c.Param("tax_rate_id")
+		var updateObj primitive.D
+
+		if taxRate.Name != nil {
+			updateObj = append(updateObj, bson.E{Key: "name", Value: taxRate.Name})
+		}
+
+		if taxRate.Rate != nil {
+			if err := validate.Var(taxRate.Rate, "gte=0,lte=100"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			updateObj = append(updateObj, bson.E{Key: "rate", Value: taxRate.Rate})
+		}
+
+		if taxRate.Inclusive != nil {
+			updateObj = append(updateObj, bson.E{Key: "inclusive", Value: taxRate.Inclusive})
+		}
+
+		if taxRate.Categories != nil {
+			updateObj = append(updateObj, bson.E{Key: "categories", Value: taxRate.Categories})
+		}
+
+		if taxRate.Active != nil {
+			updateObj = append(updateObj, bson.E{Key: "active", Value: taxRate.Active})
+		}
+
+		var err error
+		taxRate.Updated_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: taxRate.Updated_at})
+
+		filter := bson.M{"tax_rate_id": taxRateId}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := taxRateCollection.UpdateOne(
+			ctx,
+			filter,
+			bson.D{
+				{Key: "$set", Value: updateObj},
+			},
+		)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "tax rate updated failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "tax rate not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func activeTaxRates(ctx context.Context) ([]models.TaxRate, error) {
+	result, err := taxRateCollection.Find(ctx, bson.M{"active": bson.M{"$ne": false}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var taxRates []models.TaxRate
+
+	if err := result.All(ctx, &taxRates); err != nil {
+		return nil, err
+	}
+
+	return taxRates, nil
+}
@@ -0,0 +1,430 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	helper "go-restaurant-management/helpers"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TillCloseRequest struct {
+	Counted_cash *float64 `json:"counted_cash" validate:"required,gte=0"`
+	Note         *string  `json:"note"`
+}
+
+var tillSessionCollection *mongo.Collection = database.OpenCollection(database.Client, "tillSession")
+
+// managers see every till, other users only their own
+func GetTillSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := bson.M{}
+
+		if status := c.Query("status"); status != "" {
+			filter["status"] = status
+		}
+
+		if helper.CheckUserType(c, "MANAGER") != nil {
+			filter["user_id"] = c.GetString("uid")
+		} else if userId := c.Query("user_id"); userId != "" {
+			filter["user_id"] = userId
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := tillSessionCollection.Find(ctx, filter)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving till sessions from database"})
+			return
+		}
+
+		var allSessions []bson.M
+
+		if err := result.All(ctx, &allSessions); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allSessions)
+	}
+}
+
+// an open till shows the cash expected in the drawer so far
+func GetTillSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		session, err := findTillSession(ctx, c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if session.Status == "OPEN" {
+			if err := countTillCash(ctx, &session); err != nil {
+				log.Println(err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while counting the till's cash"})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, session)
+	}
+}
+
+func OpenTillSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var session models.TillSession
+
+		if err := c.BindJSON(&session); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(session)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		userId := c.GetString("uid")
+
+		_, err := openTillSession(ctx, userId)
+
+		if err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "close your open till session first"})
+			return
+		}
+
+		if err != errNoOpenTillSession {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving till sessions from database"})
+			return
+		}
+
+		session.Opened_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing opened_at"})
+			return
+		}
+
+		openingFloat := toFixed(*session.Opening_float, 2)
+
+		session.User_id = userId
+		session.Status = "OPEN"
+		session.Opening_float = &openingFloat
+		session.Payouts = []models.TillPayout{}
+		session.Cash_payments = 0
+		session.Cash_refunds = 0
+		session.Payout_total = 0
+		session.Expected_cash = openingFloat
+		session.Counted_cash = nil
+		session.Over_short = 0
+		session.Note = nil
+		session.Closed_at = nil
+		session.Updated_at = session.Opened_at
+		session.ID = primitive.NewObjectID()
+		session.Till_session_id = session.ID.Hex()
+
+		result, insertErr := tillSessionCollection.InsertOne(ctx, session)
+
+		// the unique index on open sessions stops a second one opened at the same time
+		if mongo.IsDuplicateKeyError(insertErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "close your open till session first"})
+			return
+		}
+
+		if insertErr != nil {
+			log.Println(insertErr)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "till session is not created due to some errors"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// cash taken out of the drawer for something other than a refund, e.g. paying a delivery
+func CreateTillPayout() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var payout models.TillPayout
+
+		if err := c.BindJSON(&payout); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(payout)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		session, err := findTillSession(ctx, c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if session.User_id != c.GetString("uid") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the user who opened the till can pay out of it"})
+			return
+		}
+
+		amount := toFixed(*payout.Amount, 2)
+
+		payout.Amount = &amount
+		payout.User_id = c.GetString("uid")
+		payout.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing created_at"})
+			return
+		}
+
+		result, err := tillSessionCollection.UpdateOne(
+			ctx,
+			bson.M{"till_session_id": session.Till_session_id, "status": "OPEN"},
+			bson.D{
+				{Key: "$push", Value: bson.D{{Key: "payouts", Value: payout}}},
+				{Key: "$inc", Value: bson.D{{Key: "payout_total", Value: amount}}},
+				{Key: "$set", Value: bson.D{{Key: "updated_at", Value: payout.Created_at}}},
+			},
+		)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "payout is not recorded due to some errors"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "till session is closed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// closes the till with the cash counted in the drawer and reports it over or short
+func CloseTillSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var closeRequest TillCloseRequest
+
+		if err := c.BindJSON(&closeRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(closeRequest)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		session, err := findTillSession(ctx, c)
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if session.User_id != c.GetString("uid") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the user who opened the till can close it"})
+			return
+		}
+
+		if err := countTillCash(ctx, &session); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while counting the till's cash"})
+			return
+		}
+
+		closedAt, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing closed_at"})
+			return
+		}
+
+		counted := toFixed(*closeRequest.Counted_cash, 2)
+
+		session.Status = "CLOSED"
+		session.Counted_cash = &counted
+		session.Over_short = fromCents(toCents(counted) - toCents(session.Expected_cash))
+		session.Note = closeRequest.Note
+		session.Closed_at = &closedAt
+		session.Updated_at = closedAt
+
+		result, err := tillSessionCollection.UpdateOne(
+			ctx,
+			bson.M{"till_session_id": session.Till_session_id, "status": "OPEN"},
+			bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "status", Value: session.Status},
+					{Key: "cash_payments", Value: session.Cash_payments},
+					{Key: "cash_refunds", Value: session.Cash_refunds},
+					{Key: "payout_total", Value: session.Payout_total},
+					{Key: "expected_cash", Value: session.Expected_cash},
+					{Key: "counted_cash", Value: session.Counted_cash},
+					{Key: "over_short", Value: session.Over_short},
+					{Key: "note", Value: session.Note},
+					{Key: "closed_at", Value: session.Closed_at},
+					{Key: "updated_at", Value: session.Updated_at},
+				}},
+			},
+		)
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "till session close failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "till session is already closed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, session)
+	}
+}
+
+// the till session in the request's path, if the user owns it or is a manager
+func findTillSession(ctx context.Context, c *gin.Context) (models.TillSession, error) {
+	var session models.TillSession
+
+	if err := tillSessionCollection.FindOne(ctx, bson.M{"till_session_id": c.Param("till_session_id")}).Decode(&session); err != nil {
+		return session, errors.New("till session not found")
+	}
+
+	if session.User_id != c.GetString("uid") && helper.CheckUserType(c, "MANAGER") != nil {
+		return session, errors.New("till session not found")
+	}
+
+	return session, nil
+}
+
+// the till a user has open, which takes their cash payments and refunds
+var errNoOpenTillSession = errors.New("open a till session before handling cash")
+
+func openTillSession(ctx context.Context, userId string) (*models.TillSession, error) {
+	var session models.TillSession
+
+	err := tillSessionCollection.FindOne(ctx, bson.M{"user_id": userId, "status": "OPEN"}).Decode(&session)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, errNoOpenTillSession
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// adds up the cash taken and given back through the till; the drawer should
+// hold the float plus cash payments and tips, minus cash refunds and payouts
+func countTillCash(ctx context.Context, session *models.TillSession) error {
+	sumStage := func(amount interface{}) bson.D {
+		return bson.D{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: nil},
+			{Key: "amount", Value: bson.D{{Key: "$sum", Value: amount}}},
+		}}}
+	}
+
+	var totals []struct {
+		Amount float64
+	}
+
+	paymentResult, err := paymentCollection.Aggregate(ctx, mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.D{
+			{Key: "till_session_id", Value: session.Till_session_id},
+			{Key: "method", Value: "CASH"},
+		}}},
+		sumStage(bson.D{{Key: "$add", Value: bson.A{"$amount", bson.D{{Key: "$ifNull", Value: bson.A{"$tip", 0}}}}}}),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := paymentResult.All(ctx, &totals); err != nil {
+		return err
+	}
+
+	session.Cash_payments = 0
+
+	if len(totals) > 0 {
+		session.Cash_payments = toFixed(totals[0].Amount, 2)
+	}
+
+	refundResult, err := refundCollection.Aggregate(ctx, mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.D{
+			{Key: "till_session_id", Value: session.Till_session_id},
+			{Key: "method", Value: "CASH"},
+		}}},
+		sumStage("$amount"),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	totals = nil
+
+	if err := refundResult.All(ctx, &totals); err != nil {
+		return err
+	}
+
+	session.Cash_refunds = 0
+
+	if len(totals) > 0 {
+		session.Cash_refunds = toFixed(totals[0].Amount, 2)
+	}
+
+	payouts := int64(0)
+
+	for _, payout := range session.Payouts {
+		payouts += toCents(*payout.Amount)
+	}
+
+	session.Payout_total = fromCents(payouts)
+	session.Expected_cash = fromCents(toCents(*session.Opening_float) + toCents(session.Cash_payments) - toCents(session.Cash_refunds) - payouts)
+
+	return nil
+}
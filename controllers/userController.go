@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"go-restaurant-management/database"
 	"go-restaurant-management/models"
 	"log"
@@ -22,6 +23,11 @@ var userCollection *mongo.Collection = database.OpenCollection(database.Client,
 
 func GetUsers() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if format := exportFormat(c); format != "" {
+			exportList(c, format, userCollection, bson.M{}, "users", userColumns, "password", "token", "refresh_token")
+			return
+		}
+
 		//  convert string to int
 		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
 
@@ -144,7 +150,14 @@ func Login() gin.HandlerFunc {
 			return
 		}
 
-		token, refreshToken, err := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id)
+		// users created before user types existed are staff
+		userType := "STAFF"
+
+		if foundUser.User_type != nil {
+			userType = *foundUser.User_type
+		}
+
+		token, refreshToken, err := helper.GenerateAllTokens(*foundUser.Email, *foundUser.First_name, *foundUser.Last_name, foundUser.User_id, userType)
 
 		if err != nil {
 			log.Println(err)
@@ -178,6 +191,8 @@ func Signup() gin.HandlerFunc {
 
 		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
+		// checked here as well as by the unique indexes, which are missing
+		// until the migrations have run
 		emailCount, err := userCollection.CountDocuments(ctx, bson.M{"email": user.Email})
 		defer cancel()
 
@@ -220,10 +235,16 @@ func Signup() gin.HandlerFunc {
 			return
 		}
 
+		// everyone signs up as staff; managers promote users, and the first
+		// manager is made with the promote command
+		userType := "STAFF"
+
+		user.User_type = &userType
+
 		user.ID = primitive.NewObjectID()
 		user.User_id = user.ID.Hex()
 
-		token, refreshToken, err := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id)
+		token, refreshToken, err := helper.GenerateAllTokens(*user.Email, *user.First_name, *user.Last_name, user.User_id, userType)
 
 		if err != nil {
 			log.Println(err)
@@ -237,6 +258,13 @@ func Signup() gin.HandlerFunc {
 		resultInsertionNumber, insertErr := userCollection.InsertOne(ctx, user)
 		defer cancel()
 
+		// the unique indexes on email and phone reject a second account, even
+		// when two signups race
+		if mongo.IsDuplicateKeyError(insertErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "this email or phone number already exists"})
+			return
+		}
+
 		if insertErr != nil {
 			log.Println(insertErr)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "user not created"})
@@ -247,6 +275,86 @@ func Signup() gin.HandlerFunc {
 	}
 }
 
+func UpdateUserType() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := helper.CheckUserType(c, "MANAGER"); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		userId := c.Param("user_id")
+		var user models.User
+
+		if err := c.BindJSON(&user); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Var(user.User_type, "required,eq=STAFF|eq=MANAGER"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		updated_at, err := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while parsing updated_at"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := userCollection.UpdateOne(
+			ctx,
+			bson.M{"user_id": userId},
+			bson.D{
+				{Key: "$set", Value: bson.D{
+					{Key: "user_type", Value: user.User_type},
+					{Key: "updated_at", Value: updated_at},
+				}},
+			},
+		)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "user type updated failed"})
+			return
+		}
+
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+// checks the credentials a manager typed in to authorize a refund or void, and
+// returns the manager's user id
+func approvingManager(ctx context.Context, approval *models.ManagerApproval) (string, error) {
+	var manager models.User
+
+	if err := userCollection.FindOne(ctx, bson.M{"email": approval.Email}).Decode(&manager); err != nil {
+		return "", errors.New("email or password is incorrect")
+	}
+
+	passwordIsValid, msg := VerifyPassword(*approval.Password, *manager.Password)
+
+	if !passwordIsValid {
+		return "", errors.New(msg)
+	}
+
+	if manager.User_type == nil || *manager.User_type != "MANAGER" {
+		return "", errors.New("approval requires a manager")
+	}
+
+	return manager.User_id, nil
+}
+
 func HashPassword(password string) string {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 
@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-restaurant-management/database"
+	"go-restaurant-management/models"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var wasteCollection *mongo.Collection = database.OpenCollection(database.Client, "waste")
+
+func GetWastes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		result, err := wasteCollection.Find(ctx, bson.M{})
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "error occured while retrieving waste from database"})
+			return
+		}
+
+		var allWastes []bson.M
+
+		if err := result.All(ctx, &allWastes); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, allWastes)
+	}
+}
+
+func GetWaste() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wasteId := c.Param("waste_id")
+		var waste models.Waste
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+
+		err := wasteCollection.FindOne(ctx, bson.M{"waste_id": wasteId}).Decode(&waste)
+		defer cancel()
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "waste not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, waste)
+	}
+}
+
+func CreateWaste() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var waste models.Waste
+
+		if err := c.BindJSON(&waste); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		validationError := validate.Struct(waste)
+
+		if validationError != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": validationError.Error()})
+			return
+		}
+
+		waste.Order_item_id = nil
+		waste.User_id = c.GetString("uid")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		defer cancel()
+
+		result, err := recordWaste(ctx, &waste, true)
+
+		if err == errWasteIngredientNotFound || err == errWasteFoodNotFound {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+var (
+	errWasteIngredientNotFound = errors.New("ingredient not found")
+	errWasteFoodNotFound       = errors.New("food not found")
+)
+
+// costs the waste and stores it; stock that is already gone, like a voided
+// order item that was made, is not deducted a second time
+func recordWaste(ctx context.Context, waste *models.Waste, deductStock bool) (*mongo.InsertOneResult, error) {
+	costs, err := ingredientCosts(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string]float64{}
+
+	if waste.Ingredient_id != nil {
+		var ingredient models.Ingredient
+
+		err := ingredientCollection.FindOne(ctx, bson.M{"ingredient_id": waste.Ingredient_id}).Decode(&ingredient)
+
+		if err == mongo.ErrNoDocuments {
+			return nil, errWasteIngredientNotFound
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		usage[ingredient.Ingredient_id] = *waste.Quantity
+	} else {
+		var food models.Food
+
+		err := foodCollection.FindOne(ctx, bson.M{"food_id": waste.Food_id}).Decode(&food)
+
+		if err == mongo.ErrNoDocuments {
+			return nil, errWasteFoodNotFound
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		recipe, err := recipeForFood(ctx, food.Food_id, waste.Size)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if recipe != nil {
+			for _, line := range recipe.Lines {
+				usage[*line.Ingredient_id] += *line.Amount * *waste.Quantity
+			}
+		}
+	}
+
+	waste.Lines = []models.WasteLine{}
+	waste.Cost = 0
+
+	for ingredientId, quantity := range usage {
+		cost := toFixed(quantity*costs[ingredientId], 2)
+		waste.Lines = append(waste.Lines, models.WasteLine{Ingredient_id: ingredientId, Quantity: quantity, Cost: cost})
+		waste.Cost += cost
+	}
+
+	waste.Cost = toFixed(waste.Cost, 2)
+
+	waste.Created_at, err = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	if err != nil {
+		return nil, err
+	}
+
+	waste.ID = primitive.NewObjectID()
+	waste.Waste_id = waste.ID.Hex()
+
+	var result *mongo.InsertOneResult
+
+	// the waste and its stock deduction are recorded together; called from a
+	// void, this joins the void's transaction
+	err = database.Transaction(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = wasteCollection.InsertOne(ctx, waste)
+
+		if err != nil {
+			return err
+		}
+
+		if !deductStock {
+			return nil
+		}
+
+		return adjustStock(ctx, usage, -1)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -2,25 +2,33 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"time"
 
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func DBinstance() *mongo.Client {
+	// a missing .env is fine when the variables come from the environment, as in tests
 	err := godotenv.Load(".env")
 
-	if err != nil {
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
 		log.Fatal("Error loading .env file")
 	}
 
 	MongoDb := os.Getenv("MONGODB_URL")
 
+	if MongoDb == "" {
+		MongoDb = "mongodb://localhost:27017"
+	}
+
 	// MongoDB connection
 	client, err := mongo.NewClient(options.Client().ApplyURI(MongoDb))
 
@@ -45,7 +53,52 @@ func DBinstance() *mongo.Client {
 
 var Client *mongo.Client = DBinstance()
 
+// Name of the database every collection lives in
+const Name = "go_restaurant_management"
+
 func OpenCollection(client *mongo.Client, collectionName string) *mongo.Collection {
-	var collection *mongo.Collection = client.Database("go_restaurant_management").Collection(collectionName)
+	var collection *mongo.Collection = client.Database(Name).Collection(collectionName)
 	return collection
 }
+
+// Transaction runs fn in a multi-document transaction, retrying it on
+// transient errors, so fn has to be safe to run more than once. Called with a
+// context that is already in a transaction, fn joins that transaction.
+// Transactions need MongoDB to run as a replica set.
+func Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, err := Client.StartSession()
+
+	if err != nil {
+		return err
+	}
+
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+
+	return err
+}
+
+// CheckTransactions fails unless the server can run transactions, which a
+// standalone mongod cannot: it has to be a replica set member or a mongos.
+func CheckTransactions(ctx context.Context) error {
+	var hello bson.M
+
+	err := Client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+
+	if err != nil {
+		return err
+	}
+
+	if _, ok := hello["setName"]; ok || hello["msg"] == "isdbgrid" {
+		return nil
+	}
+
+	return errors.New("MongoDB at MONGODB_URL is a standalone server; orders, invoices and payments are written in transactions, which need a replica set (a single-member one will do, see the README)")
+}
@@ -0,0 +1,175 @@
+package escpos
+
+import (
+	"bytes"
+	"strings"
+)
+
+const (
+	esc = 0x1b
+	gs  = 0x1d
+)
+
+const (
+	AlignLeft   = 0
+	AlignCenter = 1
+	AlignRight  = 2
+)
+
+// Encoder builds the byte stream of a print job for ESC/POS printers. Text is
+// sent in the printer's default code page, with characters outside ASCII
+// printed as '?'.
+type Encoder struct {
+	buffer bytes.Buffer
+}
+
+// New starts a job with the printer reset to its default settings
+func New() *Encoder {
+	encoder := &Encoder{}
+	encoder.buffer.Write([]byte{esc, '@'})
+
+	return encoder
+}
+
+func (encoder *Encoder) Text(text string) *Encoder {
+	for _, r := range text {
+		switch {
+		case r == '\n' || (r >= 32 && r < 127):
+			encoder.buffer.WriteRune(r)
+		case r == '\t':
+			encoder.buffer.WriteByte(' ')
+		default:
+			encoder.buffer.WriteByte('?')
+		}
+	}
+
+	return encoder
+}
+
+// Line prints text and moves to the next line
+func (encoder *Encoder) Line(text string) *Encoder {
+	return encoder.Text(text + "\n")
+}
+
+func (encoder *Encoder) Bold(on bool) *Encoder {
+	encoder.buffer.Write([]byte{esc, 'E', flag(on)})
+	return encoder
+}
+
+func (encoder *Encoder) Underline(on bool) *Encoder {
+	encoder.buffer.Write([]byte{esc, '-', flag(on)})
+	return encoder
+}
+
+// Inverse prints white text on black
+func (encoder *Encoder) Inverse(on bool) *Encoder {
+	encoder.buffer.Write([]byte{gs, 'B', flag(on)})
+	return encoder
+}
+
+// Size scales characters by width and height, each from 1 to 8
+func (encoder *Encoder) Size(width int, height int) *Encoder {
+	encoder.buffer.Write([]byte{gs, '!', byte((clamp(width, 1, 8)-1)<<4 | (clamp(height, 1, 8) - 1))})
+	return encoder
+}
+
+func (encoder *Encoder) Align(alignment int) *Encoder {
+	encoder.buffer.Write([]byte{esc, 'a', byte(clamp(alignment, AlignLeft, AlignRight))})
+	return encoder
+}
+
+// Feed prints the buffer and advances the paper by lines
+func (encoder *Encoder) Feed(lines int) *Encoder {
+	encoder.buffer.Write([]byte{esc, 'd', byte(clamp(lines, 0, 255))})
+	return encoder
+}
+
+// Cut feeds the paper past the cutter and cuts it, leaving a small hinge when
+// partial is set
+func (encoder *Encoder) Cut(partial bool) *Encoder {
+	mode := byte(65)
+
+	if partial {
+		mode = 66
+	}
+
+	encoder.buffer.Write([]byte{gs, 'V', mode, 3})
+
+	return encoder
+}
+
+// KickDrawer opens the cash drawer plugged into the printer's first drawer port
+func (encoder *Encoder) KickDrawer() *Encoder {
+	encoder.buffer.Write([]byte{esc, 'p', 0, 25, 250})
+	return encoder
+}
+
+// QRCode prints data as a QR code with modules of size dots (1 to 16), using
+// the printer's own QR generator (model 2, error correction M)
+func (encoder *Encoder) QRCode(data string, size int) *Encoder {
+	// function 165: select the model
+	encoder.qrFunction(65, []byte{50, 0})
+	// function 167: module size
+	encoder.qrFunction(67, []byte{byte(clamp(size, 1, 16))})
+	// function 169: error correction level
+	encoder.qrFunction(69, []byte{49})
+	// function 180: store the data, then 181: print it
+	encoder.qrFunction(80, append([]byte{48}, []byte(data)...))
+	encoder.qrFunction(81, []byte{48})
+
+	return encoder
+}
+
+func (encoder *Encoder) qrFunction(function byte, parameters []byte) {
+	length := len(parameters) + 2
+
+	encoder.buffer.Write([]byte{gs, '(', 'k', byte(length % 256), byte(length / 256), 49, function})
+	encoder.buffer.Write(parameters)
+}
+
+// Columns lays out left and right aligned text on one line of width characters,
+// cutting the left text when both do not fit
+func Columns(left string, right string, width int) string {
+	leftRunes := []rune(left)
+	rightRunes := []rune(right)
+
+	if len(rightRunes) > width {
+		rightRunes = rightRunes[:width]
+	}
+
+	space := width - len(rightRunes) - 1
+
+	if space < 0 {
+		space = 0
+	}
+
+	if len(leftRunes) > space {
+		leftRunes = leftRunes[:space]
+	}
+
+	return string(leftRunes) + strings.Repeat(" ", width-len(leftRunes)-len(rightRunes)) + string(rightRunes)
+}
+
+func (encoder *Encoder) Bytes() []byte {
+	return encoder.buffer.Bytes()
+}
+
+func flag(on bool) byte {
+	if on {
+		return 1
+	}
+
+	return 0
+}
+
+func clamp(value int, min int, max int) int {
+	if value < min {
+		return min
+	}
+
+	if value > max {
+		return max
+	}
+
+	return value
+}
@@ -0,0 +1,83 @@
+package escpos
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncoder(t *testing.T) {
+	got := New().
+		Bold(true).
+		Line("Nasi lemak").
+		Bold(false).
+		Size(2, 3).
+		Align(AlignCenter).
+		Text("Café\tx").
+		Feed(4).
+		KickDrawer().
+		Cut(true).
+		Bytes()
+
+	want := []byte{esc, '@'}
+	want = append(want, esc, 'E', 1)
+	want = append(want, []byte("Nasi lemak\n")...)
+	want = append(want, esc, 'E', 0)
+	want = append(want, gs, '!', 0x12)
+	want = append(want, esc, 'a', 1)
+	want = append(want, []byte("Caf? x")...)
+	want = append(want, esc, 'd', 4)
+	want = append(want, esc, 'p', 0, 25, 250)
+	want = append(want, gs, 'V', 66, 3)
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("got % x\nwant % x", got, want)
+	}
+}
+
+func TestEncoderClampsArguments(t *testing.T) {
+	got := New().Size(0, 9).Align(7).Feed(300).Bytes()
+	want := []byte{esc, '@', gs, '!', 0x07, esc, 'a', AlignRight, esc, 'd', 255}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("got % x\nwant % x", got, want)
+	}
+}
+
+func TestQRCode(t *testing.T) {
+	got := New().QRCode("abc", 6).Bytes()
+
+	want := []byte{esc, '@'}
+	want = append(want, gs, '(', 'k', 4, 0, 49, 65, 50, 0)
+	want = append(want, gs, '(', 'k', 3, 0, 49, 67, 6)
+	want = append(want, gs, '(', 'k', 3, 0, 49, 69, 49)
+	want = append(want, gs, '(', 'k', 6, 0, 49, 80, 48, 'a', 'b', 'c')
+	want = append(want, gs, '(', 'k', 3, 0, 49, 81, 48)
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("got % x\nwant % x", got, want)
+	}
+}
+
+func TestColumns(t *testing.T) {
+	tests := []struct {
+		left  string
+		right string
+		width int
+		want  string
+	}{
+		{"Total", "12.50", 16, "Total      12.50"},
+		{"Chicken rice", "8.00", 12, "Chicken 8.00"},
+		{"Teh tarik", "", 12, "Teh tarik   "},
+		{"", "5.00", 8, "    5.00"},
+		{"Kopi", "1234567890", 6, "123456"},
+		{"Rendang ayam", "3.00", 10, "Renda 3.00"},
+	}
+
+	for _, test := range tests {
+		got := Columns(test.left, test.right, test.width)
+
+		if got != test.want {
+			t.Errorf("Columns(%q, %q, %d) = %q, want %q", test.left, test.right, test.width, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package helpers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CheckUserType(c *gin.Context, role string) (err error) {
+	userType := c.GetString("user_type")
+
+	if userType != role {
+		err = errors.New("unauthorized to access this resource")
+		return err
+	}
+
+	return err
+}
@@ -22,10 +22,11 @@ type SignedDetails struct {
 	First_name string
 	Last_name  string
 	Uid        string
+	User_type  string
 	jwt.StandardClaims
 }
 
-func GenerateAllTokens(email string, firstName string, lastName string, uid string) (signedToken string, signedRefreshToken string, err error) {
+func GenerateAllTokens(email string, firstName string, lastName string, uid string, userType string) (signedToken string, signedRefreshToken string, err error) {
 
 	if SECRET_KEY == "" {
 		SECRET_KEY = "test"
@@ -36,6 +37,7 @@ func GenerateAllTokens(email string, firstName string, lastName string, uid stri
 		First_name: firstName,
 		Last_name:  lastName,
 		Uid:        uid,
+		User_type:  userType,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(24)).Unix(),
 		},
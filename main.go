@@ -1,6 +1,10 @@
 package main
 
 import (
+	"log"
+	"os"
+
+	"go-restaurant-management/controllers"
 	helper "go-restaurant-management/helpers"
 	"go-restaurant-management/middleware"
 	"go-restaurant-management/routes"
@@ -9,6 +13,29 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if err := checkTransactions(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := controllers.CheckInvoiceNumbering(); err != nil {
+		log.Fatal(err)
+	}
+
+	// with AUTO_MIGRATE=false migrations are left to the migrate command
+	if helper.GetEnvVariable("AUTO_MIGRATE") != "false" {
+		if err := migrate(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	port := helper.GetEnvVariable("PORT")
 
 	if port == "" {
@@ -19,6 +46,7 @@ func main() {
 	router.Use(gin.Logger())
 
 	routes.UserRoutes(router)
+	routes.WebhookRoutes(router)
 	router.Use(middleware.Authentication())
 
 	routes.FoodRoutes(router)
@@ -27,6 +55,18 @@ func main() {
 	routes.OrderRoutes(router)
 	routes.OrderItemRoutes(router)
 	routes.InvoiceRoutes(router)
+	routes.ComboRoutes(router)
+	routes.IngredientRoutes(router)
+	routes.RecipeRoutes(router)
+	routes.SupplierRoutes(router)
+	routes.PurchaseOrderRoutes(router)
+	routes.WasteRoutes(router)
+	routes.TaxRateRoutes(router)
+	routes.ServiceChargeRuleRoutes(router)
+	routes.PromotionRoutes(router)
+	routes.PrinterRoutes(router)
+	routes.TillSessionRoutes(router)
+	routes.ReportRoutes(router)
 
 	router.Run(":" + port)
 }
@@ -29,6 +29,7 @@ func Authentication() gin.HandlerFunc {
 		c.Set("first_name", claims.First_name)
 		c.Set("last_name", claims.Last_name)
 		c.Set("uid", claims.Uid)
+		c.Set("user_type", claims.User_type)
 		c.Next()
 	}
 }
@@ -0,0 +1,234 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Migration changes the database from one version to the next. Versions are
+// applied in order and each only once; a released migration is never edited,
+// a new one is added instead.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, database *mongo.Database) error
+}
+
+// Record is what the migration collection keeps of every applied version
+type Record struct {
+	Version     int        `bson:"_id" json:"version"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"` // RUNNING while being applied, then APPLIED
+	Started_at  time.Time  `json:"started_at"`
+	Applied_at  *time.Time `json:"applied_at"`
+}
+
+const collectionName = "migration"
+
+// Pending lists the migrations that are not applied, including any whose
+// record is still RUNNING
+func Pending(ctx context.Context, database *mongo.Database) ([]Migration, error) {
+	records, err := Applied(ctx, database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[int]bool{}
+
+	for _, record := range records {
+		done[record.Version] = record.Status == "APPLIED"
+	}
+
+	pending := []Migration{}
+
+	for _, migration := range sorted() {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Applied lists the records of the migration collection, applied or RUNNING
+func Applied(ctx context.Context, database *mongo.Database) ([]Record, error) {
+	result, err := database.Collection(collectionName).Find(ctx, bson.M{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := []Record{}
+
+	if err := result.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+
+	return records, nil
+}
+
+// how long Run waits for another instance to finish a migration, and how often
+// it checks on it
+var (
+	waitTimeout  = 5 * time.Minute
+	pollInterval = 2 * time.Second
+)
+
+// Run applies the pending migrations in order and returns the versions it
+// applied. A version is claimed by inserting its record first, so when several
+// instances start at once each migration still runs only once: the others
+// wait until it is applied. A failed migration gives its claim back so that it
+// is retried on the next run.
+//
+// A process that dies while applying a migration leaves its record RUNNING.
+// After waitTimeout Run stops with an error naming the version rather than
+// skip it and every later one; Release clears the record and the next run
+// applies it again.
+func Run(ctx context.Context, database *mongo.Database) ([]int, error) {
+	pending, err := Pending(ctx, database)
+
+	if err != nil {
+		return nil, err
+	}
+
+	collection := database.Collection(collectionName)
+	applied := []int{}
+
+	for _, migration := range pending {
+		claimed, err := claim(ctx, collection, migration)
+
+		if err != nil {
+			return applied, err
+		}
+
+		// applied by another instance
+		if !claimed {
+			continue
+		}
+
+		if err := migration.Up(ctx, database); err != nil {
+			if _, deleteErr := collection.DeleteOne(ctx, bson.M{"_id": migration.Version}); deleteErr != nil {
+				err = fmt.Errorf("%w (and its claim could not be released: %v)", err, deleteErr)
+			}
+
+			return applied, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+
+		now := time.Now().UTC()
+
+		_, err = collection.UpdateOne(
+			ctx,
+			bson.M{"_id": migration.Version},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "status", Value: "APPLIED"},
+				{Key: "applied_at", Value: now},
+			}}},
+		)
+
+		if err != nil {
+			return applied, err
+		}
+
+		applied = append(applied, migration.Version)
+	}
+
+	return applied, nil
+}
+
+// inserts the RUNNING record of migration, or when another instance holds it
+// waits for that instance; false means the other instance applied it
+func claim(ctx context.Context, collection *mongo.Collection, migration Migration) (bool, error) {
+	for {
+		_, err := collection.InsertOne(ctx, Record{
+			Version:     migration.Version,
+			Description: migration.Description,
+			Status:      "RUNNING",
+			Started_at:  time.Now().UTC(),
+		})
+
+		if err == nil {
+			return true, nil
+		}
+
+		if !mongo.IsDuplicateKeyError(err) {
+			return false, err
+		}
+
+		applied, err := waitForMigration(ctx, collection, migration)
+
+		// when the other instance failed and gave the claim back, it is claimed again
+		if err != nil || applied {
+			return false, err
+		}
+	}
+}
+
+// polls the record of migration until it is APPLIED (true) or deleted (false)
+func waitForMigration(ctx context.Context, collection *mongo.Collection, migration Migration) (bool, error) {
+	deadline := time.Now().Add(waitTimeout)
+
+	for {
+		var record Record
+
+		err := collection.FindOne(ctx, bson.M{"_id": migration.Version}).Decode(&record)
+
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if record.Status == "APPLIED" {
+			return true, nil
+		}
+
+		// later versions may depend on it, so stop here
+		if !time.Now().Before(deadline) {
+			return false, fmt.Errorf(
+				"migration %d (%s) has been RUNNING since %s: if no instance is applying it any more, run migrate -release %d and migrate again",
+				migration.Version, migration.Description, record.Started_at.Format(time.RFC3339), migration.Version,
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release deletes the RUNNING record of version, left by a process that died
+// while applying it, so that the next run applies the migration again. Every
+// migration has to be safe to apply again over a partial run.
+func Release(ctx context.Context, database *mongo.Database, version int) error {
+	result, err := database.Collection(collectionName).DeleteOne(ctx, bson.M{"_id": version, "status": "RUNNING"})
+
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("migration %d is not RUNNING", version)
+	}
+
+	return nil
+}
+
+func sorted() []Migration {
+	list := append([]Migration{}, all...)
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+
+	return list
+}
@@ -0,0 +1,190 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var all = []Migration{
+	{
+		Version:     1,
+		Description: "unique index on the id field of every collection",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			idFields := map[string]string{
+				"user":              "user_id",
+				"menu":              "menu_id",
+				"food":              "food_id",
+				"table":             "table_id",
+				"order":             "order_id",
+				"orderItem":         "order_item_id",
+				"invoice":           "invoice_id",
+				"payment":           "payment_id",
+				"refund":            "refund_id",
+				"combo":             "combo_id",
+				"ingredient":        "ingredient_id",
+				"recipe":            "recipe_id",
+				"supplier":          "supplier_id",
+				"purchaseOrder":     "purchase_order_id",
+				"waste":             "waste_id",
+				"taxRate":           "tax_rate_id",
+				"serviceChargeRule": "service_charge_rule_id",
+				"promotion":         "promotion_id",
+				"printer":           "printer_id",
+				"printJob":          "print_job_id",
+				"tillSession":       "till_session_id",
+				"dayReport":         "day_report_id",
+			}
+
+			for collection, field := range idFields {
+				if err := createIndexes(ctx, database, collection, uniqueIndex(bson.D{{Key: field, Value: 1}})); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "unique user email and phone, invoice number and idempotency keys",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// existing duplicates make this fail; they have to be merged by hand
+			err := createIndexes(ctx, database, "user",
+				uniqueIndex(bson.D{{Key: "email", Value: 1}}),
+				uniqueIndex(bson.D{{Key: "phone", Value: 1}}),
+			)
+
+			if err != nil {
+				return err
+			}
+
+			if err := createIndexes(ctx, database, "invoice", uniqueIndex(bson.D{{Key: "invoice_number", Value: 1}})); err != nil {
+				return err
+			}
+
+			for _, collection := range []string{"payment", "refund"} {
+				err := createIndexes(ctx, database, collection,
+					uniqueIndex(bson.D{{Key: "invoice_id", Value: 1}, {Key: "idempotency_key", Value: 1}}),
+				)
+
+				if err != nil {
+					return err
+				}
+			}
+
+			// one Z-report per business day
+			return createIndexes(ctx, database, "dayReport",
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "type", Value: 1}, {Key: "period_start", Value: 1}},
+					Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"type": "Z"}),
+				},
+			)
+		},
+	},
+	{
+		Version:     3,
+		Description: "indexes for lookups by reference",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			lookups := map[string][]bson.D{
+				"food":        {{{Key: "menu_id", Value: 1}}},
+				"order":       {{{Key: "table_id", Value: 1}}, {{Key: "server_id", Value: 1}, {Key: "status", Value: 1}}},
+				"orderItem":   {{{Key: "order_id", Value: 1}}, {{Key: "parent_order_item_id", Value: 1}}},
+				"invoice":     {{{Key: "order_id", Value: 1}}},
+				"payment":     {{{Key: "invoice_id", Value: 1}}},
+				"refund":      {{{Key: "invoice_id", Value: 1}}},
+				"recipe":      {{{Key: "food_id", Value: 1}}},
+				"printJob":    {{{Key: "status", Value: 1}}},
+				"tillSession": {{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}}},
+			}
+
+			for collection, keys := range lookups {
+				indexes := []mongo.IndexModel{}
+
+				for _, key := range keys {
+					indexes = append(indexes, mongo.IndexModel{Keys: key})
+				}
+
+				if err := createIndexes(ctx, database, collection, indexes...); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	},
+	{
+		Version:     4,
+		Description: "compound indexes for the date range reports",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			reports := map[string][]bson.D{
+				// sales, best sellers and menu engineering read sold items by day and food
+				"orderItem": {{{Key: "created_at", Value: 1}, {Key: "food_id", Value: 1}}},
+				// X and Z reports, tips and discounts
+				"invoice": {{{Key: "created_at", Value: 1}, {Key: "payment_status", Value: 1}}},
+				"payment": {{{Key: "created_at", Value: 1}, {Key: "method", Value: 1}}},
+				"refund":  {{{Key: "created_at", Value: 1}}},
+				"waste":   {{{Key: "created_at", Value: 1}}},
+			}
+
+			for collection, keys := range reports {
+				indexes := []mongo.IndexModel{}
+
+				for _, key := range keys {
+					indexes = append(indexes, mongo.IndexModel{Keys: key})
+				}
+
+				if err := createIndexes(ctx, database, collection, indexes...); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	},
+	{
+		Version:     5,
+		Description: "unique promotion voucher code",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// automatic promotions have no code and are left out of the index
+			return createIndexes(ctx, database, "promotion", uniqueIndex(bson.D{{Key: "code", Value: 1}}))
+		},
+	},
+	{
+		Version:     6,
+		Description: "one open till session per user",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// users with more than one open session have to close the extra ones first
+			return createIndexes(ctx, database, "tillSession",
+				mongo.IndexModel{
+					Keys:    bson.D{{Key: "user_id", Value: 1}},
+					Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": "OPEN"}),
+				},
+			)
+		},
+	},
+}
+
+// unique among the documents that have the field set; documents written
+// before the field existed, or holding null, are left out rather than
+// clashing on null
+func uniqueIndex(keys bson.D) mongo.IndexModel {
+	partial := bson.M{}
+
+	for _, key := range keys {
+		partial[key.Key] = bson.M{"$type": "string"}
+	}
+
+	return mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(partial),
+	}
+}
+
+func createIndexes(ctx context.Context, database *mongo.Database, collection string, indexes ...mongo.IndexModel) error {
+	_, err := database.Collection(collection).Indexes().CreateMany(ctx, indexes)
+
+	return err
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ComboSlot struct {
+	Name     *string  `json:"name" validate:"required"`
+	Food_ids []string `json:"food_ids" validate:"required,min=1"` // allowed choices for this slot
+}
+
+type Combo struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Name       *string            `json:"name" validate:"required,min=2,max=100"`
+	Price      *float64           `json:"price" validate:"required"` // bundle price
+	Slots      []ComboSlot        `json:"slots" validate:"required,min=1,dive"`
+	Created_at time.Time          `json:"created_at"`
+	Updated_at time.Time          `json:"updated_at"`
+	Combo_id   string             `json:"combo_id"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReportTax struct {
+	Tax_rate_id string  `json:"tax_rate_id"`
+	Name        string  `json:"name"`
+	Amount      float64 `json:"amount"`
+}
+
+type ReportPayment struct {
+	Method string  `json:"method"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+	Tips   float64 `json:"tips"`
+}
+
+type ReportRefund struct {
+	Method string  `json:"method"`
+	Count  int     `json:"count"`
+	Amount float64 `json:"amount"`
+}
+
+// X-report (mid-shift, never stored) or Z-report (closes the business day and
+// is stored as is, never updated)
+type DayReport struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	Type           string             `json:"type"`   // X or Z
+	Number         int64              `json:"number"` // sequence of Z-reports
+	Period_start   time.Time          `json:"period_start"`
+	Period_end     time.Time          `json:"period_end"`
+	Checks         int                `json:"checks"`      // invoices with payments taken in the period
+	Open_checks    int                `json:"open_checks"` // invoices still waiting for payment at the end of the period
+	Gross_sales    float64            `json:"gross_sales"` // paid in the period, before discounts, without tax
+	Discounts      float64            `json:"discounts"`
+	Net_sales      float64            `json:"net_sales"` // after discounts, without tax
+	Taxes          []ReportTax        `json:"taxes"`
+	Tax_total      float64            `json:"tax_total"`
+	Service_charge float64            `json:"service_charge"`
+	Total_sales    float64            `json:"total_sales"` // net sales, taxes and service charge; the payments without tips
+	Tips           float64            `json:"tips"`
+	Payments       []ReportPayment    `json:"payments"`
+	Refunds        []ReportRefund     `json:"refunds"`
+	Refund_total   float64            `json:"refund_total"`
+	Covers         int                `json:"covers"` // guests seated at the tables of the checks
+	Average_check  float64            `json:"average_check"`
+	User_id        string             `json:"user_id"` // manager who ran the report
+	Created_at     time.Time          `json:"created_at"`
+	Day_report_id  string             `json:"day_report_id"`
+}
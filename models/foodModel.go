@@ -7,12 +7,14 @@ import (
 )
 
 type Food struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	Name       *string            `json:"name" validate:"required,min=2,max=100"`
-	Price      *float64           `json:"price" validate:"required"`
-	Food_image *string            `json:"food_image" validate:"required"`
-	Created_at time.Time          `json:"created_at"`
-	Updated_at time.Time          `json:"updated_at"`
-	Food_id    string             `json:"food_id"`
-	Menu_id    *string            `json:"menu_id" validate:"required"` // reference to Menu
+	ID           primitive.ObjectID `bson:"_id"`
+	Name         *string            `json:"name" validate:"required,min=2,max=100"`
+	Price        *float64           `json:"price" validate:"required"`
+	Food_image   *string            `json:"food_image" validate:"required"`
+	Created_at   time.Time          `json:"created_at"`
+	Updated_at   time.Time          `json:"updated_at"`
+	Food_id      string             `json:"food_id"`
+	Menu_id      *string            `json:"menu_id" validate:"required"`                                           // reference to Menu
+	Availability *string            `json:"availability" validate:"omitempty,eq=AUTO|eq=AVAILABLE|eq=UNAVAILABLE"` // AUTO follows ingredient stock
+	Station      *string            `json:"station"`                                                               // kitchen station preparing the food, e.g. GRILL
 }
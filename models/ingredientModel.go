@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Ingredient struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	Name             *string            `json:"name" validate:"required,min=2,max=100"`
+	Unit             *string            `json:"unit" validate:"required"` // g, ml, pcs, ...
+	Quantity_on_hand *float64           `json:"quantity_on_hand" validate:"required"`
+	Low_stock_level  *float64           `json:"low_stock_level"`
+	Unit_cost        *float64           `json:"unit_cost"`   // cost of one unit
+	Par_level        *float64           `json:"par_level"`   // quantity to keep on hand after restocking
+	Supplier_id      *string            `json:"supplier_id"` // usual supplier, reference to Supplier
+	Created_at       time.Time          `json:"created_at"`
+	Updated_at       time.Time          `json:"updated_at"`
+	Ingredient_id    string             `json:"ingredient_id"`
+}
@@ -6,13 +6,97 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+type LineTax struct {
+	Tax_rate_id string  `json:"tax_rate_id"`
+	Amount      float64 `json:"amount"`
+}
+
+type InvoiceLine struct {
+	Order_item_id        string    `json:"order_item_id"`
+	Parent_order_item_id string    `json:"parent_order_item_id"`
+	Seat_number          *int      `json:"seat_number"`
+	Food_id              string    `json:"food_id"`
+	Name                 string    `json:"name"`
+	Category             string    `json:"category"`
+	Amount               float64   `json:"amount"`     // price charged, including inclusive taxes
+	Discount             float64   `json:"discount"`   // taken off the amount before taxes
+	Net_amount           float64   `json:"net_amount"` // amount without any tax
+	Taxes                []LineTax `json:"taxes"`
+	Total                float64   `json:"total"` // amount plus exclusive taxes
+}
+
+type InvoiceTax struct {
+	Tax_rate_id    string  `json:"tax_rate_id"`
+	Name           string  `json:"name"`
+	Rate           float64 `json:"rate"`
+	Inclusive      bool    `json:"inclusive"`
+	Taxable_amount float64 `json:"taxable_amount"`
+	Amount         float64 `json:"amount"` // sum of the rounded line taxes
+}
+
+type InvoiceServiceCharge struct {
+	Service_charge_rule_id string  `json:"service_charge_rule_id"`
+	Name                   string  `json:"name"`
+	Rate                   float64 `json:"rate"`
+	Amount                 float64 `json:"amount"`
+}
+
+type InvoiceDiscount struct {
+	Promotion_id string  `json:"promotion_id"` // empty for a manual discount
+	Name         string  `json:"name"`
+	Code         string  `json:"code"`
+	Type         string  `json:"type"`
+	Value        float64 `json:"value"`
+	Scope        string  `json:"scope"`
+	Amount       float64 `json:"amount"`
+	Reason       string  `json:"reason"`
+	Applied_by   string  `json:"applied_by"` // user who created the invoice or authorized the manual discount
+}
+
+// entry in the history of an invoice, kept for every payment, refund and void
+type InvoiceEvent struct {
+	Type         string    `json:"type"`         // PAYMENT, REFUND or VOID
+	Reference_id string    `json:"reference_id"` // payment, refund or order item
+	Amount       float64   `json:"amount"`
+	Reason       string    `json:"reason"`
+	User_id      string    `json:"user_id"`
+	Approved_by  string    `json:"approved_by"`
+	Created_at   time.Time `json:"created_at"`
+}
+
+type ManualDiscount struct {
+	Type   *string  `json:"type" validate:"required,eq=PERCENTAGE|eq=FIXED"`
+	Value  *float64 `json:"value" validate:"required,gt=0"`
+	Reason *string  `json:"reason" validate:"required,min=3"`
+}
+
 type Invoice struct {
-	ID               primitive.ObjectID `bson:"_id"`
-	Invoice_id       string             `json:"invoice_id"`
-	Order_id         string             `json:"order_id"`
-	Payment_method   *string            `json:"payment_method" validate:"required,eq=CASH|eq=CARD|eq="`
-	Payment_status   *string            `json:"paymment_status" validate:"required,eq=PENDING|eq=PAID"`
-	Payment_due_date time.Time          `json:"payment_due_date"`
-	Created_at       time.Time          `json:"created_at"`
-	Updated_at       time.Time          `json:"updated_at"`
+	ID               primitive.ObjectID     `bson:"_id"`
+	Invoice_id       string                 `json:"invoice_id"`
+	Invoice_number   string                 `json:"invoice_number"` // sequential number printed on the invoice
+	Order_id         string                 `json:"order_id"`
+	Payment_method   *string                `json:"payment_method" validate:"required,eq=CASH|eq=CARD|eq=GIFT_CARD|eq=MIXED|eq="`
+	Payment_status   *string                `json:"paymment_status" validate:"required,eq=PENDING|eq=PARTIALLY_PAID|eq=PAID|eq=REFUNDED"`
+	Payment_due_date time.Time              `json:"payment_due_date"`
+	Lines            []InvoiceLine          `json:"lines"`
+	Voucher_code     *string                `json:"voucher_code"`
+	Manual_discount  *ManualDiscount        `json:"manual_discount" bson:"-"`
+	Discounts        []InvoiceDiscount      `json:"discounts"`
+	Discount_total   float64                `json:"discount_total"`
+	Subtotal         float64                `json:"subtotal"` // sum of the net line amounts
+	Taxes            []InvoiceTax           `json:"taxes"`
+	Tax_total        float64                `json:"tax_total"`
+	Service_charges  []InvoiceServiceCharge `json:"service_charges"`
+	Service_charge   float64                `json:"service_charge"`
+	Total            float64                `json:"total"` // subtotal, taxes and service charge, without the tip
+	Tip              *float64               `json:"tip" validate:"omitempty,gte=0"`
+	Amount_paid      float64                `json:"amount_paid"`     // sum of the payments, without tips
+	Amount_refunded  float64                `json:"amount_refunded"` // refunds given back, tips included
+	History          []InvoiceEvent         `json:"history"`
+	Server_id        string                 `json:"server_id"`  // server of the order, who the tip belongs to
+	Split_mode       string                 `json:"split_mode"` // ITEM, SEAT or EVEN when the order's bill is split
+	Split_index      int                    `json:"split_index"`
+	Split_count      int                    `json:"split_count"`
+	Created_at       time.Time              `json:"created_at"`
+	Updated_at       time.Time              `json:"updated_at"`
 }
@@ -7,12 +7,19 @@ import (
 )
 
 type OrderItem struct {
-	ID            primitive.ObjectID `bson:"_id"`
-	Quantity      *string            `json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
-	Unit_price    *float64           `json:"unit_price" validate:"required"`
-	Created_at    time.Time          `json:"created_at"`
-	Updated_at    time.Time          `json:"updated_at"`
-	Food_id       *string            `json:"food_id" validate:"required"`
-	Order_item_id string             `json:"order_item_id"`
-	Order_id      string             `json:"order_id" validate:"required"`
+	ID                   primitive.ObjectID `bson:"_id"`
+	Quantity             *string            `json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
+	Unit_price           *float64           `json:"unit_price" validate:"required"`
+	Created_at           time.Time          `json:"created_at"`
+	Updated_at           time.Time          `json:"updated_at"`
+	Food_id              *string            `json:"food_id" validate:"required_without=Combo_id"`
+	Combo_id             *string            `json:"combo_id"`             // set on the bundle line of a combo
+	Parent_order_item_id *string            `json:"parent_order_item_id"` // set on the child items of a combo
+	Order_item_id        string             `json:"order_item_id"`
+	Order_id             string             `json:"order_id" validate:"required"`
+	Seat_number          *int               `json:"seat_number" validate:"omitempty,gte=1"` // seat of the guest, used to split the bill
+	Voided_at            *time.Time         `json:"voided_at"`
+	Void_reason          *string            `json:"void_reason"`
+	Voided_by            *string            `json:"voided_by"`
+	Void_approved_by     *string            `json:"void_approved_by"` // manager who authorized the void
 }
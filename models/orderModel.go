@@ -13,4 +13,8 @@ type Order struct {
 	Updated_at time.Time          `json:"updated_at"`
 	Order_id   string             `json:"order_id"`
 	Table_id   *string            `json:"table_id" validate:"required"`
+	Order_type *string            `json:"order_type" validate:"omitempty,eq=DINE_IN|eq=TAKEAWAY|eq=DELIVERY"`
+	Server_id  string             `json:"server_id"` // user who owns the order
+	Status     string             `json:"status"`    // OPEN until every invoice of the order is paid, then CLOSED
+	Closed_at  *time.Time         `json:"closed_at"`
 }
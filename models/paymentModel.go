@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Payment struct {
+	ID                 primitive.ObjectID `bson:"_id"`
+	Invoice_id         string             `json:"invoice_id"`
+	Method             *string            `json:"method" validate:"required,eq=CASH|eq=CARD|eq=GIFT_CARD"`
+	Amount             *float64           `json:"amount" validate:"required,gt=0"`                        // applied to the invoice
+	Tendered           *float64           `json:"tendered" validate:"omitempty,gt=0"`                     // cash handed over by the guest
+	Tip                *float64           `json:"tip" validate:"omitempty,gte=0"`                         // on top of the amount
+	Change_given       float64            `json:"change_given"`                                           // tendered minus amount and tip
+	Reference          *string            `json:"reference" validate:"required_if=Method GIFT_CARD"`      // gift card number
+	Card_token         *string            `json:"card_token" bson:"-" validate:"required_if=Method CARD"` // from the card terminal, never stored
+	Status             string             `json:"status"`                                                 // COMPLETED, or PENDING until the provider confirms it
+	Amount_refunded    float64            `json:"amount_refunded"`
+	Provider           string             `json:"provider"`
+	Provider_reference string             `json:"provider_reference"`
+	Idempotency_key    string             `json:"idempotency_key"`
+	User_id            string             `json:"user_id"`         // user who took the payment
+	Till_session_id    string             `json:"till_session_id"` // till that took a cash payment
+	Created_at         time.Time          `json:"created_at"`
+	Payment_id         string             `json:"payment_id"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PrintJob struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Printer_id   string             `json:"printer_id"`
+	Type         string             `json:"type"`         // RECEIPT or KITCHEN_TICKET
+	Reference_id string             `json:"reference_id"` // invoice or order printed
+	Data         []byte             `json:"-"`            // ESC/POS bytes sent to the printer
+	Status       string             `json:"status"`       // PENDING, PRINTED or FAILED
+	Attempts     int                `json:"attempts"`
+	Error        string             `json:"error"`
+	Created_at   time.Time          `json:"created_at"`
+	Printed_at   *time.Time         `json:"printed_at"`
+	Print_job_id string             `json:"print_job_id"`
+}
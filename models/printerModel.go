@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Printer struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Name       *string            `json:"name" validate:"required,min=2,max=100"`
+	Address    *string            `json:"address" validate:"required,hostname_port|hostname|ip"` // port 9100 when left out
+	Type       *string            `json:"type" validate:"required,eq=RECEIPT|eq=KITCHEN"`
+	Categories []string           `json:"categories"` // menu categories a kitchen printer takes tickets for
+	Stations   []string           `json:"stations"`   // food stations a kitchen printer takes tickets for
+	Columns    *int               `json:"columns" validate:"omitempty,gte=24,lte=64"`
+	Active     *bool              `json:"active" validate:"required"`
+	Created_at time.Time          `json:"created_at"`
+	Updated_at time.Time          `json:"updated_at"`
+	Printer_id string             `json:"printer_id"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Promotion struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Name         *string            `json:"name" validate:"required,min=2,max=100"`
+	Code         *string            `json:"code"` // voucher code, promotions without a code apply automatically
+	Type         *string            `json:"type" validate:"required,eq=PERCENTAGE|eq=FIXED"`
+	Value        *float64           `json:"value" validate:"required,gt=0"`
+	Scope        *string            `json:"scope" validate:"required,eq=ORDER|eq=LINE"`
+	Food_ids     []string           `json:"food_ids" validate:"required_if=Scope LINE"` // foods discounted by a LINE promotion
+	Min_subtotal *float64           `json:"min_subtotal"`
+	Usage_limit  *int               `json:"usage_limit" validate:"omitempty,gte=1"`
+	Usage_count  int                `json:"usage_count"`
+	Starts_at    *time.Time         `json:"starts_at"`
+	Expires_at   *time.Time         `json:"expires_at"`
+	Active       *bool              `json:"active"`
+	Created_at   time.Time          `json:"created_at"`
+	Updated_at   time.Time          `json:"updated_at"`
+	Promotion_id string             `json:"promotion_id"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RecipeLine struct {
+	Ingredient_id *string  `json:"ingredient_id" validate:"required"`
+	Amount        *float64 `json:"amount" validate:"required,gt=0"` // in the ingredient's unit
+}
+
+type Recipe struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Food_id    *string            `json:"food_id" validate:"required"`              // reference to Food
+	Size       *string            `json:"size" validate:"omitempty,eq=S|eq=M|eq=L"` // no size means the recipe applies to every size
+	Lines      []RecipeLine       `json:"lines" validate:"required,min=1,dive"`
+	Created_at time.Time          `json:"created_at"`
+	Updated_at time.Time          `json:"updated_at"`
+	Recipe_id  string             `json:"recipe_id"`
+}
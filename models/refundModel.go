@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// credentials of the manager authorizing a refund or void
+type ManagerApproval struct {
+	Email    *string `json:"email" validate:"required,email"`
+	Password *string `json:"password" validate:"required"`
+}
+
+type Refund struct {
+	ID                 primitive.ObjectID `bson:"_id"`
+	Invoice_id         string             `json:"invoice_id"`
+	Payment_id         *string            `json:"payment_id" validate:"required"`
+	Amount             *float64           `json:"amount" validate:"required,gt=0"` // may include the payment's tip
+	Reason             *string            `json:"reason" validate:"required,min=3"`
+	Approval           *ManagerApproval   `json:"approval" bson:"-" validate:"required"`
+	Approved_by        string             `json:"approved_by"` // manager who authorized the refund
+	Method             string             `json:"method"`      // method of the refunded payment
+	Status             string             `json:"status"`      // PENDING while the card provider is called, then COMPLETED or FAILED
+	Error              string             `json:"error"`       // why the provider failed the refund
+	Provider_reference string             `json:"provider_reference"`
+	Idempotency_key    string             `json:"idempotency_key"`
+	User_id            string             `json:"user_id"`         // user who issued the refund
+	Till_session_id    string             `json:"till_session_id"` // till that paid out a cash refund
+	Created_at         time.Time          `json:"created_at"`
+	Refund_id          string             `json:"refund_id"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ServiceChargeRule struct {
+	ID                     primitive.ObjectID `bson:"_id"`
+	Name                   *string            `json:"name" validate:"required,min=2,max=100"`
+	Rate                   *float64           `json:"rate" validate:"required,gt=0,lte=100"` // percentage of the subtotal
+	Min_guests             *int               `json:"min_guests" validate:"omitempty,gte=1"` // applies from this party size on
+	Order_types            []string           `json:"order_types"`                           // applies to these order types, empty means every type
+	Active                 *bool              `json:"active"`
+	Created_at             time.Time          `json:"created_at"`
+	Updated_at             time.Time          `json:"updated_at"`
+	Service_charge_rule_id string             `json:"service_charge_rule_id"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TaxRate struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	Name        *string            `json:"name" validate:"required,min=2,max=100"`
+	Rate        *float64           `json:"rate" validate:"required,gte=0,lte=100"` // percentage, 6 means 6%
+	Inclusive   *bool              `json:"inclusive" validate:"required"`          // true when menu prices already include the tax
+	Categories  []string           `json:"categories"`                             // menu categories taxed, empty means every category
+	Active      *bool              `json:"active"`
+	Created_at  time.Time          `json:"created_at"`
+	Updated_at  time.Time          `json:"updated_at"`
+	Tax_rate_id string             `json:"tax_rate_id"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TillPayout struct {
+	Amount     *float64  `json:"amount" validate:"required,gt=0"`
+	Reason     *string   `json:"reason" validate:"required,min=3"`
+	User_id    string    `json:"user_id"`
+	Created_at time.Time `json:"created_at"`
+}
+
+type TillSession struct {
+	ID              primitive.ObjectID `bson:"_id"`
+	User_id         string             `json:"user_id"` // user who opened the till and owns it
+	Status          string             `json:"status"`  // OPEN or CLOSED
+	Opening_float   *float64           `json:"opening_float" validate:"required,gte=0"`
+	Payouts         []TillPayout       `json:"payouts"`
+	Cash_payments   float64            `json:"cash_payments"` // cash taken in, tips included
+	Cash_refunds    float64            `json:"cash_refunds"`
+	Payout_total    float64            `json:"payout_total"`
+	Expected_cash   float64            `json:"expected_cash"` // float, plus payments, minus refunds and payouts
+	Counted_cash    *float64           `json:"counted_cash"`
+	Over_short      float64            `json:"over_short"` // counted minus expected, negative when short
+	Note            *string            `json:"note"`
+	Opened_at       time.Time          `json:"opened_at"`
+	Closed_at       *time.Time         `json:"closed_at"`
+	Updated_at      time.Time          `json:"updated_at"`
+	Till_session_id string             `json:"till_session_id"`
+}
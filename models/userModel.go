@@ -14,6 +14,7 @@ type User struct {
 	Email         *string            `json:"email" validate:"email,required"`
 	Avatar        *string            `json:"avatar"`
 	Phone         *string            `json:"phone" validate:"required"`
+	User_type     *string            `json:"user_type" validate:"omitempty,eq=STAFF|eq=MANAGER"`
 	Token         *string            `json:"token"`
 	Refresh_token *string            `json:"refresh_token"`
 	Created_at    time.Time          `json:"created_at"`
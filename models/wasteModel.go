@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WasteLine struct {
+	Ingredient_id string  `json:"ingredient_id"`
+	Quantity      float64 `json:"quantity"`
+	Cost          float64 `json:"cost"`
+}
+
+type Waste struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Reason        *string            `json:"reason" validate:"required,eq=DROPPED|eq=EXPIRED|eq=RETURNED|eq=SPOILED|eq=OTHER"`
+	Ingredient_id *string            `json:"ingredient_id" validate:"required_without=Food_id"` // raw stock thrown away
+	Food_id       *string            `json:"food_id" validate:"required_without=Ingredient_id"` // a prepared dish thrown away
+	Size          *string            `json:"size" validate:"omitempty,eq=S|eq=M|eq=L"`
+	Quantity      *float64           `json:"quantity" validate:"required,gt=0"`
+	Note          *string            `json:"note"`
+	Order_item_id *string            `json:"order_item_id"` // set when a voided order item is flagged as waste
+	Lines         []WasteLine        `json:"lines"`         // ingredients deducted from stock
+	Cost          float64            `json:"cost"`
+	User_id       string             `json:"user_id"` // user who reported the waste
+	Created_at    time.Time          `json:"created_at"`
+	Waste_id      string             `json:"waste_id"`
+}
@@ -0,0 +1,197 @@
+package payments
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"sync"
+)
+
+// test card tokens understood by the mock provider
+const (
+	MockTokenDecline = "tok_decline"
+	MockTokenTimeout = "tok_timeout"
+	MockTokenPending = "tok_pending"
+)
+
+type mockPayment struct {
+	amount   int64
+	captured int64
+	refunded int64
+	status   string
+}
+
+// MockProvider is a deterministic in-memory provider for running offline: every
+// card is approved except the test tokens above, and references are derived from
+// the idempotency key so retries always get the same answer.
+type MockProvider struct {
+	mutex    sync.Mutex
+	payments map[string]*mockPayment
+	results  map[string]Result
+}
+
+func NewMockProvider() *MockProvider {
+	return &MockProvider{
+		payments: map[string]*mockPayment{},
+		results:  map[string]Result{},
+	}
+}
+
+func (provider *MockProvider) Name() string {
+	return "mock"
+}
+
+func (provider *MockProvider) Authorize(ctx context.Context, request AuthorizeRequest) (Result, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if result, ok := provider.results["authorize:"+request.Idempotency_key]; ok {
+		if result.Status == StatusDeclined {
+			return result, ErrDeclined
+		}
+
+		return result, nil
+	}
+
+	if request.Amount <= 0 {
+		return Result{}, errors.New("amount must be positive")
+	}
+
+	switch request.Card_token {
+	case MockTokenTimeout:
+		return Result{}, ErrTimeout
+	case MockTokenDecline:
+		result := Result{Reference: mockReference(request.Idempotency_key), Status: StatusDeclined, Message: "insufficient funds"}
+		provider.results["authorize:"+request.Idempotency_key] = result
+		return result, ErrDeclined
+	}
+
+	status := StatusAuthorized
+
+	if request.Card_token == MockTokenPending {
+		status = StatusPending
+	}
+
+	result := Result{Reference: mockReference(request.Idempotency_key), Status: status}
+	provider.payments[result.Reference] = &mockPayment{amount: request.Amount, status: status}
+	provider.results["authorize:"+request.Idempotency_key] = result
+
+	return result, nil
+}
+
+func (provider *MockProvider) Capture(ctx context.Context, reference string, amount int64, idempotencyKey string) (Result, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if result, ok := provider.results["capture:"+idempotencyKey]; ok {
+		return result, nil
+	}
+
+	payment, ok := provider.payments[reference]
+
+	if !ok {
+		return Result{}, errors.New("payment " + reference + " not found")
+	}
+
+	// a pending payment is captured once the webhook confirms it
+	if payment.status == StatusPending {
+		return Result{Reference: reference, Status: StatusPending}, nil
+	}
+
+	if payment.status != StatusAuthorized || amount > payment.amount {
+		return Result{}, errors.New("payment " + reference + " cannot be captured")
+	}
+
+	payment.captured = amount
+	payment.status = StatusCaptured
+
+	result := Result{Reference: reference, Status: StatusCaptured}
+	provider.results["capture:"+idempotencyKey] = result
+
+	return result, nil
+}
+
+func (provider *MockProvider) Void(ctx context.Context, reference string, idempotencyKey string) (Result, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if result, ok := provider.results["void:"+idempotencyKey]; ok {
+		return result, nil
+	}
+
+	payment, ok := provider.payments[reference]
+
+	if !ok {
+		return Result{}, errors.New("payment " + reference + " not found")
+	}
+
+	if payment.status != StatusAuthorized && payment.status != StatusPending {
+		return Result{}, errors.New("payment " + reference + " cannot be voided")
+	}
+
+	payment.status = StatusVoided
+
+	result := Result{Reference: reference, Status: StatusVoided}
+	provider.results["void:"+idempotencyKey] = result
+
+	return result, nil
+}
+
+func (provider *MockProvider) Refund(ctx context.Context, reference string, amount int64, idempotencyKey string) (Result, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if result, ok := provider.results["refund:"+idempotencyKey]; ok {
+		return result, nil
+	}
+
+	payment, ok := provider.payments[reference]
+
+	if !ok {
+		return Result{}, errors.New("payment " + reference + " not found")
+	}
+
+	if payment.status != StatusCaptured && payment.status != StatusRefunded {
+		return Result{}, errors.New("payment " + reference + " cannot be refunded")
+	}
+
+	if amount <= 0 || payment.refunded+amount > payment.captured {
+		return Result{}, errors.New("refund exceeds the captured amount")
+	}
+
+	payment.refunded += amount
+
+	if payment.refunded == payment.captured {
+		payment.status = StatusRefunded
+	}
+
+	result := Result{Reference: mockReference(idempotencyKey), Status: StatusRefunded}
+	provider.results["refund:"+idempotencyKey] = result
+
+	return result, nil
+}
+
+// Confirm settles a pending payment the way an asynchronous provider would
+// before calling the webhook; the payment webhook calls it, as nothing else
+// settles the mock's payments
+func (provider *MockProvider) Confirm(reference string) error {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	payment, ok := provider.payments[reference]
+
+	if !ok || payment.status != StatusPending {
+		return errors.New("pending payment " + reference + " not found")
+	}
+
+	payment.captured = payment.amount
+	payment.status = StatusCaptured
+
+	return nil
+}
+
+func mockReference(idempotencyKey string) string {
+	sum := sha1.Sum([]byte(idempotencyKey))
+	return "mock_" + hex.EncodeToString(sum[:8])
+}
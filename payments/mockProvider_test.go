@@ -0,0 +1,144 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMockProviderApprovesCards(t *testing.T) {
+	provider := NewMockProvider()
+	ctx := context.Background()
+
+	authorization, err := provider.Authorize(ctx, AuthorizeRequest{Card_token: "tok_visa", Amount: 1500, Idempotency_key: "pay1"})
+
+	if err != nil || authorization.Status != StatusAuthorized {
+		t.Fatalf("authorize: %+v, %v", authorization, err)
+	}
+
+	capture, err := provider.Capture(ctx, authorization.Reference, 1500, "pay1")
+
+	if err != nil || capture.Status != StatusCaptured {
+		t.Fatalf("capture: %+v, %v", capture, err)
+	}
+
+	refund, err := provider.Refund(ctx, authorization.Reference, 500, "refund1")
+
+	if err != nil || refund.Status != StatusRefunded {
+		t.Fatalf("refund: %+v, %v", refund, err)
+	}
+
+	if _, err := provider.Refund(ctx, authorization.Reference, 1001, "refund2"); err == nil {
+		t.Error("refunding more than was captured should fail")
+	}
+}
+
+func TestMockProviderDecline(t *testing.T) {
+	provider := NewMockProvider()
+	request := AuthorizeRequest{Card_token: MockTokenDecline, Amount: 1500, Idempotency_key: "pay1"}
+
+	result, err := provider.Authorize(context.Background(), request)
+
+	if !errors.Is(err, ErrDeclined) || result.Status != StatusDeclined {
+		t.Fatalf("authorize: %+v, %v", result, err)
+	}
+
+	// a retry is declined the same way
+	replay, err := provider.Authorize(context.Background(), request)
+
+	if !errors.Is(err, ErrDeclined) || replay != result {
+		t.Errorf("replay: %+v, %v, want %+v", replay, err, result)
+	}
+}
+
+func TestMockProviderTimeout(t *testing.T) {
+	provider := NewMockProvider()
+
+	_, err := provider.Authorize(context.Background(), AuthorizeRequest{Card_token: MockTokenTimeout, Amount: 1500, Idempotency_key: "pay1"})
+
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("authorize: %v, want a timeout", err)
+	}
+}
+
+func TestMockProviderPending(t *testing.T) {
+	provider := NewMockProvider()
+	ctx := context.Background()
+
+	authorization, err := provider.Authorize(ctx, AuthorizeRequest{Card_token: MockTokenPending, Amount: 1500, Idempotency_key: "pay1"})
+
+	if err != nil || authorization.Status != StatusPending {
+		t.Fatalf("authorize: %+v, %v", authorization, err)
+	}
+
+	capture, err := provider.Capture(ctx, authorization.Reference, 1500, "pay1")
+
+	if err != nil || capture.Status != StatusPending {
+		t.Fatalf("capture of a pending payment: %+v, %v", capture, err)
+	}
+
+	if _, err := provider.Refund(ctx, authorization.Reference, 1500, "refund1"); err == nil {
+		t.Fatal("a pending payment should not be refundable")
+	}
+
+	if err := provider.Confirm(authorization.Reference); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := provider.Confirm(authorization.Reference); err == nil {
+		t.Error("confirming twice should fail")
+	}
+
+	if _, err := provider.Refund(ctx, authorization.Reference, 1500, "refund1"); err != nil {
+		t.Errorf("refund after confirming: %v", err)
+	}
+
+	var provided PaymentProvider = provider
+
+	if _, ok := provided.(Confirmer); !ok {
+		t.Error("the mock provider should confirm pending payments")
+	}
+}
+
+func TestMockProviderIdempotentReplay(t *testing.T) {
+	provider := NewMockProvider()
+	ctx := context.Background()
+	request := AuthorizeRequest{Card_token: "tok_visa", Amount: 1500, Idempotency_key: "pay1"}
+
+	first, err := provider.Authorize(ctx, request)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a retry with a different amount still gets the first answer
+	request.Amount = 9999
+	replay, err := provider.Authorize(ctx, request)
+
+	if err != nil || replay != first {
+		t.Fatalf("replay: %+v, %v, want %+v", replay, err, first)
+	}
+
+	if _, err := provider.Capture(ctx, first.Reference, 1500, "pay1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.Capture(ctx, first.Reference, 1500, "pay1"); err != nil {
+		t.Fatalf("capture replay: %v", err)
+	}
+
+	// the same refund sent twice only refunds once
+	for i := 0; i < 2; i++ {
+		if _, err := provider.Refund(ctx, first.Reference, 1000, "refund1"); err != nil {
+			t.Fatalf("refund attempt %d: %v", i+1, err)
+		}
+	}
+
+	if _, err := provider.Refund(ctx, first.Reference, 500, "refund2"); err != nil {
+		t.Errorf("the rest of the payment should still be refundable: %v", err)
+	}
+
+	if mockReference("pay1") != first.Reference || mockReference("pay2") == first.Reference {
+		t.Error("references should follow the idempotency key")
+	}
+}
@@ -0,0 +1,83 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	helper "go-restaurant-management/helpers"
+)
+
+// statuses reported by a provider for a card payment
+const (
+	StatusAuthorized = "AUTHORIZED"
+	StatusCaptured   = "CAPTURED"
+	StatusPending    = "PENDING" // the provider confirms the payment later through the webhook
+	StatusDeclined   = "DECLINED"
+	StatusVoided     = "VOIDED"
+	StatusRefunded   = "REFUNDED"
+)
+
+var ErrDeclined = errors.New("card payment declined")
+var ErrTimeout = errors.New("payment provider timed out")
+
+type AuthorizeRequest struct {
+	Card_token      string
+	Amount          int64 // in cents
+	Currency        string
+	Idempotency_key string
+}
+
+type Result struct {
+	Reference string
+	Status    string
+	Message   string
+}
+
+// PaymentProvider is implemented by every card payment gateway. Calls with an
+// idempotency key that was already used return the result of the first call.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, request AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, reference string, amount int64, idempotencyKey string) (Result, error)
+	Void(ctx context.Context, reference string, idempotencyKey string) (Result, error)
+	Refund(ctx context.Context, reference string, amount int64, idempotencyKey string) (Result, error)
+}
+
+// Confirmer is implemented by providers that settle a pending payment when its
+// webhook arrives instead of before sending it, like the mock provider
+type Confirmer interface {
+	Confirm(reference string) error
+}
+
+var providers = map[string]func() PaymentProvider{
+	"mock": func() PaymentProvider { return NewMockProvider() },
+}
+
+var current PaymentProvider
+
+// Register makes a provider selectable through the PAYMENT_PROVIDER variable
+func Register(name string, factory func() PaymentProvider) {
+	providers[name] = factory
+}
+
+// Provider returns the provider named by PAYMENT_PROVIDER, the mock provider by default
+func Provider() (PaymentProvider, error) {
+	if current != nil {
+		return current, nil
+	}
+
+	name := helper.GetEnvVariable("PAYMENT_PROVIDER")
+
+	if name == "" {
+		name = "mock"
+	}
+
+	factory, ok := providers[name]
+
+	if !ok {
+		return nil, errors.New("unknown payment provider " + name)
+	}
+
+	current = factory()
+
+	return current, nil
+}
@@ -0,0 +1,123 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// width of every Courier glyph as a share of the font size
+const glyphWidth = 0.6
+
+// Document writes a PDF 1.4 file using the standard Courier fonts, which every
+// viewer provides, so no font has to be embedded
+type Document struct {
+	width  float64
+	height float64
+	pages  []*Page
+}
+
+type Page struct {
+	height  float64
+	content bytes.Buffer
+}
+
+// New starts a document whose pages measure width by height points (1/72 inch)
+func New(width float64, height float64) *Document {
+	return &Document{width: width, height: height}
+}
+
+func (document *Document) AddPage() *Page {
+	page := &Page{height: document.height}
+	document.pages = append(document.pages, page)
+
+	return page
+}
+
+// TextWidth is the width in points of text set in Courier at the given size
+func TextWidth(text string, size float64) float64 {
+	return float64(len([]rune(text))) * glyphWidth * size
+}
+
+// Text draws a line of text whose baseline is y points below the top of the page
+func (page *Page) Text(x float64, y float64, size float64, bold bool, text string) {
+	font := "F1"
+
+	if bold {
+		font = "F2"
+	}
+
+	fmt.Fprintf(&page.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, page.height-y, escape(text))
+}
+
+// Line draws a thin line between two points measured from the top of the page
+func (page *Page) Line(x1 float64, y1 float64, x2 float64, y2 float64) {
+	fmt.Fprintf(&page.content, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, page.height-y1, x2, page.height-y2)
+}
+
+func (document *Document) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+
+	// objects 1 to 4 are the catalog, page tree and fonts, then each page and its content
+	var kids []string
+
+	for i := range document.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 5+i*2))
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range document.pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			document.width, page.height, 6+i*2,
+		))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := out.Len()
+
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// escapes a string for a PDF literal, writing characters outside Latin-1 as '?'
+func escape(text string) string {
+	var out strings.Builder
+
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r < 32:
+			out.WriteByte(' ')
+		case r < 128:
+			out.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&out, "\\%03o", r)
+		default:
+			out.WriteByte('?')
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ComboRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/combos/:combo_id", controller.GetCombo())
+	incomingRoutes.GET("/combos", controller.GetCombos())
+	incomingRoutes.POST("/combos", controller.CreateCombo())
+	incomingRoutes.PATCH("/combos/:combo_id", controller.UpdateCombo())
+}
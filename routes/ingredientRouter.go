@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func IngredientRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/ingredients/:ingredient_id", controller.GetIngredient())
+	incomingRoutes.GET("/ingredients", controller.GetIngredients())
+	incomingRoutes.POST("/ingredients", controller.CreateIngredient())
+	incomingRoutes.PATCH("/ingredients/:ingredient_id", controller.UpdateIngredient())
+	incomingRoutes.GET("/stockLevels", controller.GetStockLevels())
+}
@@ -10,5 +10,12 @@ func InvoiceRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/invoices/:invoice_id", controller.GetInvoice())
 	incomingRoutes.GET("/invoices", controller.GetInvoices())
 	incomingRoutes.POST("/invoices", controller.CreateInvoice())
+	incomingRoutes.POST("/invoiceSplits", controller.CreateSplitInvoices())
+	incomingRoutes.GET("/invoices/:invoice_id/pdf", controller.GetInvoicePDF())
+	incomingRoutes.POST("/invoices/:invoice_id/print", controller.PrintInvoice())
+	incomingRoutes.GET("/invoices/:invoice_id/payments", controller.GetInvoicePayments())
+	incomingRoutes.POST("/invoices/:invoice_id/payments", controller.CreatePayment())
+	incomingRoutes.GET("/invoices/:invoice_id/refunds", controller.GetInvoiceRefunds())
+	incomingRoutes.POST("/invoices/:invoice_id/refunds", controller.CreateRefund())
 	incomingRoutes.PATCH("invoices/:invoice_id", controller.UpdateInvoice())
 }
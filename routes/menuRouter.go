@@ -9,6 +9,8 @@ import (
 func MenuRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/menus/:menu_id", controller.GetMenu())
 	incomingRoutes.GET("/menus", controller.GetMenus())
+	incomingRoutes.GET("/menus/:menu_id/foods", controller.GetMenuFoods())
 	incomingRoutes.POST("/menus", controller.CreateMenu())
+	incomingRoutes.POST("/menus/import", controller.ImportMenus())
 	incomingRoutes.PATCH("/menus/:menu_id", controller.UpdateMenu())
 }
@@ -12,4 +12,5 @@ func OrderItemRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/orderItemsByOrder/:order_id", controller.GetOrderItemsByOrder())
 	incomingRoutes.POST("/orderItems", controller.CreateOrderItem())
 	incomingRoutes.PATCH("/orderItems/:orderItem_id", controller.UpdateOrderItem())
+	incomingRoutes.POST("/orderItems/:orderItem_id/void", controller.VoidOrderItem())
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PrinterRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/printers/:printer_id", controller.GetPrinter())
+	incomingRoutes.GET("/printers", controller.GetPrinters())
+	incomingRoutes.POST("/printers", controller.CreatePrinter())
+	incomingRoutes.PATCH("/printers/:printer_id", controller.UpdatePrinter())
+	incomingRoutes.GET("/printJobs/:print_job_id", controller.GetPrintJob())
+	incomingRoutes.GET("/printJobs", controller.GetPrintJobs())
+	incomingRoutes.POST("/printJobs/:print_job_id/retry", controller.RetryPrintJob())
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PromotionRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/promotions/:promotion_id", controller.GetPromotion())
+	incomingRoutes.GET("/promotions", controller.GetPromotions())
+	incomingRoutes.POST("/promotions", controller.CreatePromotion())
+	incomingRoutes.PATCH("/promotions/:promotion_id", controller.UpdatePromotion())
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PurchaseOrderRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/purchaseOrders/:purchase_order_id", controller.GetPurchaseOrder())
+	incomingRoutes.GET("/purchaseOrders", controller.GetPurchaseOrders())
+	incomingRoutes.POST("/purchaseOrders", controller.CreatePurchaseOrder())
+	incomingRoutes.PATCH("/purchaseOrders/:purchase_order_id", controller.UpdatePurchaseOrder())
+	incomingRoutes.POST("/purchaseOrders/:purchase_order_id/send", controller.SendPurchaseOrder())
+	incomingRoutes.POST("/purchaseOrders/:purchase_order_id/receive", controller.ReceivePurchaseOrder())
+	incomingRoutes.GET("/suggestedPurchaseOrders", controller.GetSuggestedPurchaseOrders())
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReportRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/reports/foodCost", controller.GetFoodCostReport())
+	incomingRoutes.GET("/reports/menuEngineering", controller.GetMenuEngineeringReport())
+	incomingRoutes.GET("/reports/waste", controller.GetWasteReport())
+	incomingRoutes.GET("/reports/tips", controller.GetTipsReport())
+	incomingRoutes.GET("/reports/discounts", controller.GetDiscountsReport())
+	incomingRoutes.GET("/reports/refunds", controller.GetRefundsReport())
+	incomingRoutes.GET("/reports/sales", controller.GetSalesReport())
+	incomingRoutes.GET("/reports/bestSellers", controller.GetBestSellersReport())
+	incomingRoutes.GET("/reports/slowMovers", controller.GetSlowMoversReport())
+	incomingRoutes.GET("/reports/attachRate", controller.GetAttachRateReport())
+	incomingRoutes.GET("/reports/x", controller.GetXReport())
+	incomingRoutes.GET("/reports/z/:day_report_id", controller.GetZReport())
+	incomingRoutes.GET("/reports/z", controller.GetZReports())
+	incomingRoutes.POST("/reports/z", controller.CreateZReport())
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ServiceChargeRuleRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/serviceChargeRules/:service_charge_rule_id", controller.GetServiceChargeRule())
+	incomingRoutes.GET("/serviceChargeRules", controller.GetServiceChargeRules())
+	incomingRoutes.POST("/serviceChargeRules", controller.CreateServiceChargeRule())
+	incomingRoutes.PATCH("/serviceChargeRules/:service_charge_rule_id", controller.UpdateServiceChargeRule())
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SupplierRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/suppliers/:supplier_id", controller.GetSupplier())
+	incomingRoutes.GET("/suppliers", controller.GetSuppliers())
+	incomingRoutes.POST("/suppliers", controller.CreateSupplier())
+	incomingRoutes.PATCH("/suppliers/:supplier_id", controller.UpdateSupplier())
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TaxRateRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/taxRates/:tax_rate_id", controller.GetTaxRate())
+	incomingRoutes.GET("/taxRates", controller.GetTaxRates())
+	incomingRoutes.POST("/taxRates", controller.CreateTaxRate())
+	incomingRoutes.PATCH("/taxRates/:tax_rate_id", controller.UpdateTaxRate())
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TillSessionRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/tillSessions/:till_session_id", controller.GetTillSession())
+	incomingRoutes.GET("/tillSessions", controller.GetTillSessions())
+	incomingRoutes.POST("/tillSessions", controller.OpenTillSession())
+	incomingRoutes.POST("/tillSessions/:till_session_id/payouts", controller.CreateTillPayout())
+	incomingRoutes.POST("/tillSessions/:till_session_id/close", controller.CloseTillSession())
+}
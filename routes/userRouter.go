@@ -12,4 +12,5 @@ func UserRoutes(incomingRoutes *gin.Engine) {
 	incomingRoutes.GET("/users", middleware.Authentication(), controller.GetUsers())
 	incomingRoutes.POST("/users/login", controller.Login())
 	incomingRoutes.POST("/users/signup", controller.Signup())
+	incomingRoutes.PATCH("/users/:user_id/userType", middleware.Authentication(), controller.UpdateUserType())
 }
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WasteRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.GET("/waste/:waste_id", controller.GetWaste())
+	incomingRoutes.GET("/waste", controller.GetWastes())
+	incomingRoutes.POST("/waste", controller.CreateWaste())
+}
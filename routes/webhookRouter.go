@@ -0,0 +1,13 @@
+package routes
+
+import (
+	controller "go-restaurant-management/controllers"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webhooks are called by outside services, which sign their requests instead of
+// sending a user token
+func WebhookRoutes(incomingRoutes *gin.Engine) {
+	incomingRoutes.POST("/webhooks/payments", controller.PaymentWebhookHandler())
+}
@@ -0,0 +1,150 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// Cell is one value of a row; numbers are stored as numbers so that
+// spreadsheets can sum them, anything else as text
+type Cell struct {
+	Value  string
+	Number bool
+}
+
+// Writer streams a workbook with a single sheet. Rows are written to the
+// archive as they come, so the whole sheet is never held in memory.
+type Writer struct {
+	archive *zip.Writer
+	sheet   io.Writer
+}
+
+const contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const rootRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookRelationships = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
+
+// NewWriter writes the parts of the workbook that come before the rows
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	archive := zip.NewWriter(w)
+
+	workbook := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + escape(sheetTitle(sheetName)) + `" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", rootRelationships},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", workbookRelationships},
+	}
+
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Writer{archive: archive, sheet: sheet}, nil
+}
+
+func (writer *Writer) WriteRow(cells []Cell) error {
+	var row strings.Builder
+
+	row.WriteString("<row>")
+
+	for _, cell := range cells {
+		switch {
+		case cell.Value == "":
+			row.WriteString("<c/>")
+		case cell.Number:
+			row.WriteString("<c><v>" + cell.Value + "</v></c>")
+		default:
+			row.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">` + escape(cell.Value) + "</t></is></c>")
+		}
+	}
+
+	row.WriteString("</row>")
+
+	_, err := io.WriteString(writer.sheet, row.String())
+
+	return err
+}
+
+// Close ends the sheet and writes the archive's directory
+func (writer *Writer) Close() error {
+	if _, err := io.WriteString(writer.sheet, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+
+	return writer.archive.Close()
+}
+
+// sheet names are at most 31 characters and cannot hold []:*?/\
+func sheetTitle(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+
+		return r
+	}, name)
+
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+
+	if name == "" {
+		name = "Sheet1"
+	}
+
+	return name
+}
+
+// escape also replaces characters XML cannot hold, such as control characters
+func escape(text string) string {
+	var buffer bytes.Buffer
+
+	xml.EscapeText(&buffer, []byte(text))
+
+	return buffer.String()
+}